@@ -0,0 +1,55 @@
+package main
+
+import (
+	"time"
+)
+
+// 12MHz crystal, one machine cycle every 12 oscillator periods (1us)
+const DEFAULT_CLOCK_HZ int = 12_000_000
+const DEFAULT_CLOCKS_PER_CYCLE int = 12
+
+// Advances the on-chip peripherals one machine cycle at a time
+func (m *Machine) tick(cycles int) {
+	for i := 0; i < cycles; i++ {
+		m.cycles++
+
+		m.applyPinEvents()
+		m.sampleExternalInterrupts()
+		t1Overflows := m.tickTimers()
+		t2Overflows := 0
+		if m.hasTimer2() {
+			t2Overflows = m.tickTimer2()
+		}
+		m.tickSerial(t1Overflows, t2Overflows)
+		m.tickPeripherals()
+	}
+}
+
+// Machine cycles executed since the last power-on reset
+func (m *Machine) Cycles() uint64 {
+	return m.cycles
+}
+
+// Duration of one machine cycle at the configured clock
+func (m *Machine) CycleTime() time.Duration {
+	return m.cyclesToDuration(1)
+}
+
+// Simulated time spent executing since the last power-on reset
+func (m *Machine) Elapsed() time.Duration {
+	return m.cyclesToDuration(m.cycles)
+}
+
+func (m *Machine) cyclesToDuration(cycles uint64) time.Duration {
+	if m.ClockHz <= 0 {
+		return 0
+	}
+
+	// split into whole seconds and remainder so long runs don't overflow
+	clocks := cycles * uint64(m.ClocksPerCycle)
+	hz := uint64(m.ClockHz)
+	secs := clocks / hz
+	rem := clocks % hz
+
+	return time.Duration(secs)*time.Second + time.Duration(rem*uint64(time.Second)/hz)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpcodeCycles(t *testing.T) {
+	cases := []struct {
+		Opcode byte
+		Cycles int
+	}{
+		{Opcode: 0x00, Cycles: 1}, // NOP
+		{Opcode: 0x04, Cycles: 1}, // INC A
+		{Opcode: 0xE5, Cycles: 1}, // MOV A,direct
+		{Opcode: 0x75, Cycles: 2}, // MOV direct,#data
+		{Opcode: 0x85, Cycles: 2}, // MOV direct,direct
+		{Opcode: 0x01, Cycles: 2}, // AJMP
+		{Opcode: 0xF1, Cycles: 2}, // ACALL
+		{Opcode: 0x12, Cycles: 2}, // LCALL
+		{Opcode: 0x22, Cycles: 2}, // RET
+		{Opcode: 0xDF, Cycles: 2}, // DJNZ R7,rel
+		{Opcode: 0x93, Cycles: 2}, // MOVC A,@A+DPTR
+		{Opcode: 0xF0, Cycles: 2}, // MOVX @DPTR,A
+		{Opcode: 0xA2, Cycles: 1}, // MOV C,bit
+		{Opcode: 0x92, Cycles: 2}, // MOV bit,C
+		{Opcode: 0xA4, Cycles: 4}, // MUL AB
+		{Opcode: 0x84, Cycles: 4}, // DIV AB
+	}
+
+	for _, tc := range cases {
+		op := OPCODES[tc.Opcode]
+		if op.Cycles != tc.Cycles {
+			t.Errorf("%02X (%s): expected %d cycle(s), got %d", tc.Opcode, op.Name, tc.Cycles, op.Cycles)
+		}
+	}
+
+	for opcode, op := range OPCODES {
+		if op.Cycles != 1 && op.Cycles != 2 && op.Cycles != 4 {
+			t.Errorf("%02X (%s): invalid cycle count %d", opcode, op.Name, op.Cycles)
+		}
+	}
+}
+
+func TestDelayLoopTiming(t *testing.T) {
+	program := []byte{
+		0x7F, 0x64, // MOV R7,#100   1 cycle
+		0xDF, 0xFE, // DJNZ R7,$     2 cycles x 100
+		0x80, 0xFE, // SJMP $        2 cycles
+	}
+
+	cases := []struct {
+		ClockHz  int
+		Expected time.Duration
+	}{
+		{ClockHz: 12_000_000, Expected: 203 * time.Microsecond},
+		{ClockHz: 6_000_000, Expected: 406 * time.Microsecond},
+		{ClockHz: 11_059_200, Expected: 220_269 * time.Nanosecond},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.ClockHz = tc.ClockHz
+		if err := vm.Load(program); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := vm.Run(0); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.Cycles() != 203 {
+			t.Errorf("expected 203 machine cycles, got %d", vm.Cycles())
+		}
+
+		if vm.Elapsed() != tc.Expected {
+			t.Errorf("%dHz: expected %s elapsed, got %s", tc.ClockHz, tc.Expected, vm.Elapsed())
+		}
+	}
+}
+
+func TestCyclesReset(t *testing.T) {
+	vm := NewMachine()
+	vm.Feed([]byte{0xA4})
+
+	if vm.Cycles() != 4 {
+		t.Errorf("expected MUL AB to take 4 cycles, got %d", vm.Cycles())
+	}
+
+	vm.WarmReset()
+	if vm.Cycles() != 4 {
+		t.Errorf("expected warm reset to keep the cycle count, got %d", vm.Cycles())
+	}
+
+	vm.Reset()
+	if vm.Cycles() != 0 {
+		t.Errorf("expected power-on reset to clear the cycle count, got %d", vm.Cycles())
+	}
+
+	if vm.CycleTime() != time.Microsecond {
+		t.Errorf("expected 1us machine cycle at 12MHz, got %s", vm.CycleTime())
+	}
+}
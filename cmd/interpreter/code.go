@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+)
+
+// The 8051 can address up to 64KB of program memory
+const CODE_MAX_SIZE int = 64 * 1024
+
+// What a read from code memory past the end of the loaded image returns
+type CodeOverrun int
+
+const (
+	CODE_OVERRUN_ERROR CodeOverrun = iota // report the read as an error
+	CODE_OVERRUN_WRAP                     // wrap around to the start of the image
+	CODE_OVERRUN_FF                       // read as 0xFF, like erased EPROM/flash
+)
+
+func (o CodeOverrun) String() string {
+	switch o {
+	case CODE_OVERRUN_ERROR:
+		return "error"
+	case CODE_OVERRUN_WRAP:
+		return "wrap"
+	case CODE_OVERRUN_FF:
+		return "0xFF"
+	}
+
+	return fmt.Sprintf("CodeOverrun(%d)", int(o))
+}
+
+// Read-only program memory, used both for instruction fetch and MOVC
+type CodeMemory struct {
+	rom     []byte
+	size    int         // number of bytes in the loaded image
+	Overrun CodeOverrun // behaviour when reading past the loaded image
+}
+
+func NewCodeMemory(capacity int) (*CodeMemory, error) {
+	if capacity < 1 || capacity > CODE_MAX_SIZE {
+		return nil, fmt.Errorf("code memory capacity must be between 1B and %dB, got %dB", CODE_MAX_SIZE, capacity)
+	}
+
+	code := CodeMemory{
+		rom:     make([]byte, capacity),
+		Overrun: CODE_OVERRUN_ERROR,
+	}
+
+	return &code, nil
+}
+
+// Replaces the contents of code memory with image, starting at 0x0000
+func (c *CodeMemory) Load(image []byte) error {
+	if len(image) > len(c.rom) {
+		return fmt.Errorf("program of %dB exceeds program memory capacity of %dB", len(image), len(c.rom))
+	}
+
+	n := copy(c.rom, image)
+	clear(c.rom[n:])
+	c.size = n
+
+	return nil
+}
+
+// Size of the loaded image in bytes
+func (c *CodeMemory) Size() int {
+	return c.size
+}
+
+// How many bytes of code memory the device has
+func (c *CodeMemory) Capacity() int {
+	return len(c.rom)
+}
+
+func (c *CodeMemory) Read(addr uint16) (byte, error) {
+	if int(addr) < c.size {
+		return c.rom[addr], nil
+	}
+
+	switch c.Overrun {
+	case CODE_OVERRUN_WRAP:
+		if c.size == 0 {
+			return 0, fmt.Errorf("code address %#04x: no program loaded", addr)
+		}
+		return c.rom[int(addr)%c.size], nil
+	case CODE_OVERRUN_FF:
+		return 0xFF, nil
+	}
+
+	return 0, fmt.Errorf("code address %#04x is past the end of the loaded image (%dB)", addr, c.size)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewCodeMemoryCapacity(t *testing.T) {
+	cases := []struct {
+		Capacity int
+		Ok       bool
+	}{
+		{Capacity: 0, Ok: false},
+		{Capacity: 1, Ok: true},
+		{Capacity: 4 * 1024, Ok: true},
+		{Capacity: CODE_MAX_SIZE, Ok: true},
+		{Capacity: CODE_MAX_SIZE + 1, Ok: false},
+	}
+
+	for _, tc := range cases {
+		code, err := NewCodeMemory(tc.Capacity)
+		if tc.Ok && err != nil {
+			t.Errorf("capacity %d: unexpected error: %s", tc.Capacity, err)
+		}
+
+		if !tc.Ok && err == nil {
+			t.Errorf("capacity %d: expected error, nil given", tc.Capacity)
+		}
+
+		if tc.Ok && code.Capacity() != tc.Capacity {
+			t.Errorf("expected capacity to be %d, got %d", tc.Capacity, code.Capacity())
+		}
+	}
+}
+
+func TestCodeMemoryLoad(t *testing.T) {
+	code, _ := NewCodeMemory(4)
+
+	if err := code.Load([]byte{1, 2, 3, 4, 5}); err == nil {
+		t.Errorf("expected error when loading an image larger than capacity, nil given")
+	}
+
+	if err := code.Load([]byte{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a smaller image must not leave the old one behind
+	if err := code.Load([]byte{9}); err != nil {
+		t.Fatal(err)
+	}
+
+	code.Overrun = CODE_OVERRUN_WRAP
+	for addr := uint16(0); addr < 4; addr++ {
+		val, err := code.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if val != 9 {
+			t.Errorf("expected %#04x to read 9 from the new image, got %d", addr, val)
+		}
+	}
+}
+
+func TestCodeMemoryOverrun(t *testing.T) {
+	image := []byte{0x10, 0x20, 0x30}
+
+	cases := []struct {
+		Overrun  CodeOverrun
+		Addr     uint16
+		Expected byte
+		Err      bool
+	}{
+		{Overrun: CODE_OVERRUN_ERROR, Addr: 0x0002, Expected: 0x30},
+		{Overrun: CODE_OVERRUN_ERROR, Addr: 0x0003, Err: true},
+		{Overrun: CODE_OVERRUN_ERROR, Addr: 0xFFFF, Err: true},
+		{Overrun: CODE_OVERRUN_WRAP, Addr: 0x0003, Expected: 0x10},
+		{Overrun: CODE_OVERRUN_WRAP, Addr: 0x0007, Expected: 0x20},
+		{Overrun: CODE_OVERRUN_FF, Addr: 0x0003, Expected: 0xFF},
+		{Overrun: CODE_OVERRUN_FF, Addr: 0xFFFF, Expected: 0xFF},
+	}
+
+	for _, tc := range cases {
+		code, _ := NewCodeMemory(CODE_MAX_SIZE)
+		code.Load(image)
+		code.Overrun = tc.Overrun
+
+		val, err := code.Read(tc.Addr)
+		if tc.Err {
+			if err == nil {
+				t.Errorf("%s: expected error reading %#04x, nil given", tc.Overrun, tc.Addr)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.Overrun, err)
+		}
+
+		if val != tc.Expected {
+			t.Errorf("%s: expected %#04x to read %#02x, got %#02x", tc.Overrun, tc.Addr, tc.Expected, val)
+		}
+	}
+}
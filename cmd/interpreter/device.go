@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Vendor SFRs that are only plain registers here, their
+// behaviour is not modelled
+const SFR_DP1L uint8 = 0x84   // AT89S52 second data pointer
+const SFR_DP1H uint8 = 0x85   // AT89S52 second data pointer
+const SFR_AUXR uint8 = 0x8E   // AT89S52 auxiliary register
+const SFR_AUXR1 uint8 = 0xA2  // AT89S52 data pointer select
+const SFR_WDTRST uint8 = 0xA6 // AT89S52 watchdog reset
+const SFR_DPL1 uint8 = 0x84   // DS89C4x0 second data pointer
+const SFR_DPH1 uint8 = 0x85   // DS89C4x0 second data pointer
+const SFR_DPS uint8 = 0x86    // DS89C4x0 data pointer select
+const SFR_CKCON uint8 = 0x8E  // DS89C4x0 clock control
+const SFR_PMR uint8 = 0xC4    // DS89C4x0 power management
+const SFR_STATUS uint8 = 0xC5 // DS89C4x0 status
+const SFR_TA uint8 = 0xC7     // DS89C4x0 timed access
+const SFR_WDCON uint8 = 0xD8  // DS89C4x0 watchdog control
+
+// SFRs every 8051 has
+var SFRS_8051 = []uint8{
+	SFR_ACC, SFR_B, SFR_DPH, SFR_DPL, SFR_IE, SFR_IP,
+	SFR_P0, SFR_P1, SFR_P2, SFR_P3, SFR_PCON, SFR_PSW,
+	SFR_SCON, SFR_SBUF, SFR_SP, SFR_TMOD, SFR_TCON,
+	SFR_TL0, SFR_TH0, SFR_TL1, SFR_TH1,
+}
+
+// The 8052 adds timer 2. T2MOD (and with it up/down counting)
+// only came with the later CMOS parts
+var SFRS_8052 = append(slices.Clone(SFRS_8051),
+	SFR_T2CON, SFR_RCAP2L, SFR_RCAP2H, SFR_TL2, SFR_TH2,
+)
+
+var SFRS_AT89S52 = append(slices.Clone(SFRS_8052),
+	SFR_T2MOD, SFR_DP1L, SFR_DP1H, SFR_AUXR, SFR_AUXR1, SFR_WDTRST,
+)
+
+var SFRS_DS89C4X0 = append(slices.Clone(SFRS_8052),
+	SFR_T2MOD, SFR_DPL1, SFR_DPH1, SFR_DPS, SFR_CKCON, SFR_PMR, SFR_STATUS, SFR_TA, SFR_WDCON,
+)
+
+// What sets one member of the 8051 family apart from another
+type Device struct {
+	Name string
+
+	CodeSize int // program memory the firmware has to fit in
+	IRAMSize int // internal RAM, 128B or 256B
+	XRAMSize int // MOVX RAM on the chip itself, 0 if the board has to add it
+
+	SFRs        []uint8           // SFR addresses the chip implements, nil for all of them
+	ResetValues map[uint8]byte    // on top of SFR_RESET_VALUES
+	Interrupts  []InterruptSource // in polling order
+
+	ClocksPerCycle int // oscillator periods per machine cycle
+
+	// Machine cycles an instruction takes, nil for the classic 8051 count
+	InstructionCycles func(opcode byte, op Opcode) int
+
+	// CKCON T0M/T1M/T2M switch the timers from fosc/12 to fosc/4
+	TimerClockSelect bool
+}
+
+// Everything the interpreter can model at once, what NewMachine builds
+var DEVICE_GENERIC = Device{
+	Name:           "generic",
+	CodeSize:       CODE_MAX_SIZE,
+	IRAMSize:       int(LOC_UPPER_RAM) + IRAM_UPPER_SIZE,
+	XRAMSize:       XRAM_MAX_SIZE,
+	SFRs:           nil, // plain registers where nothing is modelled
+	Interrupts:     INTERRUPTS_8052,
+	ClocksPerCycle: DEFAULT_CLOCKS_PER_CYCLE,
+}
+
+// ROMless, the code runs from a 64KB EPROM on the external bus
+var DEVICE_8031 = Device{
+	Name:           "8031",
+	CodeSize:       CODE_MAX_SIZE,
+	IRAMSize:       int(LOC_UPPER_RAM),
+	SFRs:           SFRS_8051,
+	Interrupts:     INTERRUPTS_8051,
+	ClocksPerCycle: 12,
+}
+
+var DEVICE_8051 = Device{
+	Name:           "8051",
+	CodeSize:       4 * 1024,
+	IRAMSize:       int(LOC_UPPER_RAM),
+	SFRs:           SFRS_8051,
+	Interrupts:     INTERRUPTS_8051,
+	ClocksPerCycle: 12,
+}
+
+var DEVICE_8052 = Device{
+	Name:           "8052",
+	CodeSize:       8 * 1024,
+	IRAMSize:       int(LOC_UPPER_RAM) + IRAM_UPPER_SIZE,
+	SFRs:           SFRS_8052,
+	Interrupts:     INTERRUPTS_8052,
+	ClocksPerCycle: 12,
+}
+
+var DEVICE_AT89S52 = Device{
+	Name:           "AT89S52",
+	CodeSize:       8 * 1024,
+	IRAMSize:       int(LOC_UPPER_RAM) + IRAM_UPPER_SIZE,
+	SFRs:           SFRS_AT89S52,
+	Interrupts:     INTERRUPTS_8052,
+	ClocksPerCycle: 12,
+}
+
+// The DS89C4x0 runs one machine cycle per clock while its timers stay at
+// fosc/12 unless CKCON says otherwise. The second serial port, the extra
+// external interrupts and the watchdog are not modelled
+func ds89c4x0(name string, codeSize int) Device {
+	return Device{
+		Name:             name,
+		CodeSize:         codeSize,
+		IRAMSize:         int(LOC_UPPER_RAM) + IRAM_UPPER_SIZE,
+		XRAMSize:         1024,
+		SFRs:             SFRS_DS89C4X0,
+		ResetValues:      map[uint8]byte{SFR_CKCON: 0x01}, // one MOVX stretch cycle
+		Interrupts:       INTERRUPTS_8052,
+		ClocksPerCycle:    1,
+		InstructionCycles: ds89c4x0Cycles,
+		TimerClockSelect:  true,
+	}
+}
+
+// The DS89C4x0 core fetches a byte per cycle, so an instruction takes one
+// cycle per byte plus one to reload PC when it can branch, taken or not.
+// MOVX is counted without the CKCON stretch cycles
+func ds89c4x0Cycles(opcode byte, op Opcode) int {
+	switch opcode {
+	case 0xA4: // MUL AB
+		return 9
+	case 0x84: // DIV AB
+		return 10
+	case 0x22, 0x32, 0x73, 0x83, 0x93: // RET, RETI, JMP @A+DPTR, MOVC
+		return 3
+	case 0xC0, 0xD0, 0xE0, 0xE2, 0xE3, 0xF0, 0xF2, 0xF3: // PUSH, POP, MOVX
+		return 2
+	}
+
+	if isBranch(opcode) {
+		return op.Size + 1
+	}
+
+	return op.Size
+}
+
+// Jumps, calls and conditional branches with an address or offset operand
+func isBranch(opcode byte) bool {
+	switch {
+	case opcode&0x0F == 0x01: // AJMP, ACALL
+		return true
+	case opcode == 0x02, opcode == 0x12, opcode == 0x80: // LJMP, LCALL, SJMP
+		return true
+	case opcode <= 0x70 && opcode&0x0F == 0x00: // JBC, JB, JNB, JC, JNC, JZ, JNZ
+		return opcode != 0x00
+	case opcode >= 0xB4 && opcode <= 0xBF: // CJNE
+		return true
+	case opcode == 0xD5, opcode >= 0xD8 && opcode <= 0xDF: // DJNZ
+		return true
+	}
+
+	return false
+}
+
+var DEVICE_DS89C430 = ds89c4x0("DS89C430", 16*1024)
+var DEVICE_DS89C440 = ds89c4x0("DS89C440", 32*1024)
+var DEVICE_DS89C450 = ds89c4x0("DS89C450", 64*1024)
+
+// Built-in profiles, see LookupDevice
+var DEVICES = []Device{
+	DEVICE_8031,
+	DEVICE_8051,
+	DEVICE_8052,
+	DEVICE_AT89S52,
+	DEVICE_DS89C430,
+	DEVICE_DS89C440,
+	DEVICE_DS89C450,
+}
+
+// Finds a built-in profile by part name, ignoring case
+func LookupDevice(name string) (Device, error) {
+	for _, dev := range DEVICES {
+		if strings.EqualFold(dev.Name, name) {
+			return dev, nil
+		}
+	}
+
+	return Device{}, fmt.Errorf("unknown device %q", name)
+}
+
+// Builds a machine with the memories, SFRs, interrupts and timing of dev
+func NewMachineFor(dev Device) (*Machine, error) {
+	code, err := NewCodeMemory(dev.CodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("device %s: %w", dev.Name, err)
+	}
+
+	xram, err := NewExternalMemory(dev.XRAMSize)
+	if err != nil {
+		return nil, fmt.Errorf("device %s: %w", dev.Name, err)
+	}
+
+	if dev.ClocksPerCycle < 1 {
+		return nil, fmt.Errorf("device %s: clocks per machine cycle must be at least 1, got %d", dev.Name, dev.ClocksPerCycle)
+	}
+
+	vm := Machine{
+		Code:   code,
+		XRAM:   xram,
+		XBus:   NewXDataBus(),
+		Data:   make([]byte, 256, 256), // pre-allocate 256B RAM
+		device: dev,
+
+		interrupts: slices.Clone(dev.Interrupts),
+
+		ClockHz:        DEFAULT_CLOCK_HZ,
+		ClocksPerCycle: dev.ClocksPerCycle,
+
+		t0Level: true,
+		t1Level: true,
+
+		t2Level:   true,
+		t2exLevel: true,
+
+		int0Level: true,
+		int1Level: true,
+	}
+
+	if err := vm.SetIRAMSize(dev.IRAMSize); err != nil {
+		return nil, fmt.Errorf("device %s: %w", dev.Name, err)
+	}
+
+	if dev.SFRs == nil {
+		for loc := int(LOC_UPPER_RAM); loc < len(vm.sfrs); loc++ {
+			vm.sfrs[loc] = true
+		}
+	}
+
+	for _, loc := range dev.SFRs {
+		if loc < LOC_UPPER_RAM {
+			return nil, fmt.Errorf("device %s: %#02x is not an SFR address", dev.Name, loc)
+		}
+		vm.sfrs[loc] = true
+	}
+
+	vm.Reset()
+
+	return &vm, nil
+}
+
+// Profile the machine was built for
+func (m *Machine) Device() Device {
+	return m.device
+}
+
+func (m *Machine) checkSFR(loc uint8) error {
+	if loc >= LOC_UPPER_RAM && !m.sfrs[loc] {
+		return fmt.Errorf("SFR %#02x is not implemented on %s", loc, m.device.Name)
+	}
+
+	return nil
+}
+
+func (m *Machine) hasTimer2() bool {
+	return m.sfrs[SFR_T2CON]
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceProfiles(t *testing.T) {
+	cases := []struct {
+		Name           string
+		CodeSize       int
+		IRAMSize       int
+		XRAMSize       int
+		ClocksPerCycle int
+		Timer2         bool
+		T2MOD          bool
+	}{
+		{Name: "8031", CodeSize: 64 * 1024, IRAMSize: 128, XRAMSize: 0, ClocksPerCycle: 12},
+		{Name: "8051", CodeSize: 4 * 1024, IRAMSize: 128, XRAMSize: 0, ClocksPerCycle: 12},
+		{Name: "8052", CodeSize: 8 * 1024, IRAMSize: 256, XRAMSize: 0, ClocksPerCycle: 12, Timer2: true},
+		{Name: "at89s52", CodeSize: 8 * 1024, IRAMSize: 256, XRAMSize: 0, ClocksPerCycle: 12, Timer2: true, T2MOD: true},
+		{Name: "DS89C430", CodeSize: 16 * 1024, IRAMSize: 256, XRAMSize: 1024, ClocksPerCycle: 1, Timer2: true, T2MOD: true},
+		{Name: "DS89C440", CodeSize: 32 * 1024, IRAMSize: 256, XRAMSize: 1024, ClocksPerCycle: 1, Timer2: true, T2MOD: true},
+		{Name: "DS89C450", CodeSize: 64 * 1024, IRAMSize: 256, XRAMSize: 1024, ClocksPerCycle: 1, Timer2: true, T2MOD: true},
+	}
+
+	for _, tc := range cases {
+		dev, err := LookupDevice(tc.Name)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		vm, err := NewMachineFor(dev)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		if vm.Code.Capacity() != tc.CodeSize {
+			t.Errorf("%s: expected %dB of code memory, got %dB", tc.Name, tc.CodeSize, vm.Code.Capacity())
+		}
+
+		if vm.IRAMSize() != tc.IRAMSize {
+			t.Errorf("%s: expected %dB of internal RAM, got %dB", tc.Name, tc.IRAMSize, vm.IRAMSize())
+		}
+
+		if vm.XRAM.Size() != tc.XRAMSize {
+			t.Errorf("%s: expected %dB of external RAM, got %dB", tc.Name, tc.XRAMSize, vm.XRAM.Size())
+		}
+
+		if vm.ClocksPerCycle != tc.ClocksPerCycle {
+			t.Errorf("%s: expected %d clocks per cycle, got %d", tc.Name, tc.ClocksPerCycle, vm.ClocksPerCycle)
+		}
+
+		if err := vm.WriteMem(SFR_T2CON, 0); (err == nil) != tc.Timer2 {
+			t.Errorf("%s: expected T2CON to be implemented: %t, write returned %v", tc.Name, tc.Timer2, err)
+		}
+
+		if _, err := vm.ReadMem(SFR_T2MOD); (err == nil) != tc.T2MOD {
+			t.Errorf("%s: expected T2MOD to be implemented: %t, read returned %v", tc.Name, tc.T2MOD, err)
+		}
+
+		// every part has the 8051 SFRs and their reset values
+		if vm.P1() != 0xFF || vm.SP() != 0x07 {
+			t.Errorf("%s: expected reset P1 0xFF and SP 0x07, got %#02x and %#02x", tc.Name, vm.P1(), vm.SP())
+		}
+	}
+}
+
+func TestLookupDeviceUnknown(t *testing.T) {
+	if _, err := LookupDevice("8086"); err == nil {
+		t.Errorf("expected an unknown device to fail")
+	}
+}
+
+func TestNewMachineForInvalid(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Modify func(dev *Device)
+	}{
+		{Name: "no code memory", Modify: func(dev *Device) { dev.CodeSize = 0 }},
+		{Name: "XRAM too large", Modify: func(dev *Device) { dev.XRAMSize = XRAM_MAX_SIZE + 1 }},
+		{Name: "odd IRAM size", Modify: func(dev *Device) { dev.IRAMSize = 192 }},
+		{Name: "no clocks per cycle", Modify: func(dev *Device) { dev.ClocksPerCycle = 0 }},
+		{Name: "SFR in RAM", Modify: func(dev *Device) { dev.SFRs = []uint8{0x30} }},
+	}
+
+	for _, tc := range cases {
+		dev := DEVICE_8052
+		tc.Modify(&dev)
+
+		if _, err := NewMachineFor(dev); err == nil {
+			t.Errorf("%s: expected NewMachineFor to fail", tc.Name)
+		}
+	}
+}
+
+func TestDevice8051(t *testing.T) {
+	vm, err := NewMachineFor(DEVICE_8051)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vm.Load(make([]byte, 4*1024+1)); err == nil {
+		t.Errorf("expected a 4KB+1 image not to fit an 8051")
+	}
+
+	if err := vm.Load(make([]byte, 0x40)); err != nil {
+		t.Fatal(err)
+	}
+
+	// MOV R0,#90h ; MOV A,@R0 - there is no upper RAM
+	if err := vm.Feed([]byte{0x78, 0x90}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Feed([]byte{0xE6}); err == nil {
+		t.Errorf("expected @R0 at 0x90 to fail on an 8051")
+	}
+
+	// MOV T2CON,#04h
+	if err := vm.Feed([]byte{0x75, SFR_T2CON, T2CON_TR2_MASK}); err == nil {
+		t.Errorf("expected MOV to T2CON to fail on an 8051")
+	}
+
+	// even with the bits forced, there is no timer 2 to run or interrupt
+	vm.Data[SFR_T2CON] = T2CON_TR2_MASK | T2CON_TF2_MASK
+	vm.Data[SFR_IE] = 0xFF
+
+	if err := vm.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.Data[SFR_TL2] != 0 {
+		t.Errorf("expected timer 2 not to count on an 8051, TL2 is %#02x", vm.Data[SFR_TL2])
+	}
+
+	if vm.PC == 0x002B {
+		t.Errorf("expected no timer 2 interrupt on an 8051")
+	}
+}
+
+func TestDeviceDS89C4x0Timing(t *testing.T) {
+	vm, err := NewMachineFor(DEVICE_DS89C450)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feedNops(t, vm, 12)
+
+	// one clock per machine cycle, 12 cycles at 12MHz take 1us
+	if us := vm.Elapsed().Microseconds(); us != 1 {
+		t.Errorf("expected 12 cycles to take 1us, got %dus", us)
+	}
+
+	program := []byte{
+		0x00,       // 0000: NOP
+		0x7F, 0x0B, // 0001: MOV R7,#0Bh
+		0x75, 0x30, 0x55, // 0003: MOV 30h,#55h
+		0xDF, 0xFB, // 0006: DJNZ R7,0003
+		0x80, 0xFE, // 0008: SJMP $
+	}
+
+	cases := []struct {
+		Device  Device
+		Cycles  uint64
+		Elapsed time.Duration
+	}{
+		// 1 + 1 + 11 * (2 + 2) + 2 classic machine cycles of 12 clocks
+		{Device: DEVICE_8052, Cycles: 48, Elapsed: 48 * time.Microsecond},
+		// 1 + 2 + 11 * (3 + 3) + 3, a cycle per byte and one more per branch
+		{Device: DEVICE_DS89C450, Cycles: 72, Elapsed: 6 * time.Microsecond},
+	}
+
+	for _, tc := range cases {
+		vm, err := NewMachineFor(tc.Device)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := vm.Load(program); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := vm.Run(0); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.Cycles() != tc.Cycles {
+			t.Errorf("%s: expected the loop to take %d cycles, got %d", tc.Device.Name, tc.Cycles, vm.Cycles())
+		}
+
+		if vm.Elapsed() != tc.Elapsed {
+			t.Errorf("%s: expected the loop to take %s, got %s", tc.Device.Name, tc.Elapsed, vm.Elapsed())
+		}
+	}
+}
+
+func TestDeviceDS89C4x0Timers(t *testing.T) {
+	cases := []struct {
+		Name     string
+		CKCON    byte
+		Cycles   int
+		Expected byte
+	}{
+		// timers stay at fosc/12 although a machine cycle is one clock
+		{Name: "fosc/12", CKCON: 0x01, Cycles: 120, Expected: 10},
+		{Name: "fosc/4 with T0M", CKCON: 0x01 | CKCON_T0M_MASK, Cycles: 120, Expected: 30},
+		{Name: "T1M leaves timer 0 alone", CKCON: 0x01 | CKCON_T1M_MASK, Cycles: 120, Expected: 10},
+	}
+
+	for _, tc := range cases {
+		vm, err := NewMachineFor(DEVICE_DS89C450)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.Data[SFR_CKCON] != 0x01 {
+			t.Errorf("%s: expected CKCON to reset to 0x01, got %#02x", tc.Name, vm.Data[SFR_CKCON])
+		}
+
+		vm.WriteMem(SFR_CKCON, tc.CKCON)
+		vm.WriteMem(SFR_TMOD, 0x01)
+		vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+
+		feedNops(t, vm, tc.Cycles)
+
+		if vm.TL0() != tc.Expected {
+			t.Errorf("%s: expected TL0 to be %d after %d cycles, got %d", tc.Name, tc.Expected, tc.Cycles, vm.TL0())
+		}
+	}
+}
+
+func TestDeviceDS89C4x0Timer1Baud(t *testing.T) {
+	classic, _ := NewMachineFor(DEVICE_8052)
+	ds, _ := NewMachineFor(DEVICE_DS89C450)
+
+	for _, vm := range []*Machine{classic, ds} {
+		vm.WriteMem(SFR_SCON, 0x40) // mode 1
+		vm.WriteMem(SFR_TMOD, 0x20) // timer 1 auto-reload
+		vm.WriteMem(SFR_TH1, 0xFD)
+	}
+
+	// the same firmware gets the same baud rate on both parts
+	if classic.SerialBaudRate() != ds.SerialBaudRate() {
+		t.Errorf("expected the same baud rate on the 8052 and DS89C450, got %f and %f", classic.SerialBaudRate(), ds.SerialBaudRate())
+	}
+
+	// TH1=FFh overflows every 12 clocks, a 10-bit frame takes 320 overflows
+	ds.WriteMem(SFR_TH1, 0xFF)
+	ds.WriteMem(SFR_TL1, 0xFF)
+	ds.WriteMem(SFR_TCON, TCON_TR1_MASK)
+	ds.WriteMem(SFR_SBUF, 0x55)
+
+	feedNops(t, ds, 320*12-1)
+	if ds.SCON()&SCON_TI_MASK != 0 {
+		t.Fatalf("expected the frame to still be shifting out")
+	}
+
+	feedNops(t, ds, 1)
+	if ds.SCON()&SCON_TI_MASK == 0 {
+		t.Errorf("expected TI after %d cycles", 320*12)
+	}
+}
+
+func TestDeviceDS89C4x0Timer2Baud(t *testing.T) {
+	vm, err := NewMachineFor(DEVICE_DS89C450)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm.WriteMem(SFR_SCON, 0x40) // mode 1
+	vm.WriteMem(SFR_RCAP2H, 0xFF)
+	vm.WriteMem(SFR_RCAP2L, 0xFA)
+	vm.WriteMem(SFR_TH2, 0xFF)
+	vm.WriteMem(SFR_TL2, 0xFA)
+	vm.WriteMem(SFR_T2CON, T2CON_TCLK_MASK|T2CON_TR2_MASK)
+	vm.WriteMem(SFR_SBUF, 0x55)
+
+	// fosc/2: one count every 2 clocks, an overflow every 12,
+	// 160 overflows for the frame
+	feedNops(t, vm, 4)
+	if vm.TL2() != 0xFC {
+		t.Errorf("expected TL2 at 0xfc after 4 clocks, got %#02x", vm.TL2())
+	}
+
+	feedNops(t, vm, 160*12-5)
+	if vm.SCON()&SCON_TI_MASK != 0 {
+		t.Fatalf("expected the frame to still be shifting out")
+	}
+
+	feedNops(t, vm, 1)
+	if vm.SCON()&SCON_TI_MASK == 0 {
+		t.Errorf("expected TI after %d cycles", 160*12)
+	}
+
+	// 12MHz / 32 / 6, as on the classic part
+	if rate := vm.SerialBaudRate(); rate != 62500 {
+		t.Errorf("expected 62500 baud, got %f", rate)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+)
+
+// A level driven onto port pins from outside, applied once
+// the cycle counter reaches Cycle
+type pinEvent struct {
+	Cycle uint64
+	Port  int
+	Mask  byte
+	High  bool
+}
+
+// Drives the INT0 (P3.2) pin from outside the chip,
+// the level is sampled at the next machine cycle
+func (m *Machine) SetINT0(high bool) {
+	m.setP3Input(P3_INT0_MASK, high)
+}
+
+// Drives the INT1 (P3.3) pin from outside the chip
+func (m *Machine) SetINT1(high bool) {
+	m.setP3Input(P3_INT1_MASK, high)
+}
+
+// Drives INT0 to the given level once Cycles() reaches cycle,
+// so whole waveforms (button bounce etc.) can be queued up front
+func (m *Machine) ScheduleINT0(cycle uint64, high bool) {
+	m.schedulePin(cycle, 3, P3_INT0_MASK, high)
+}
+
+func (m *Machine) ScheduleINT1(cycle uint64, high bool) {
+	m.schedulePin(cycle, 3, P3_INT1_MASK, high)
+}
+
+// Drives any port pin to the given level once Cycles() reaches cycle
+func (m *Machine) SchedulePin(cycle uint64, port int, bit int, high bool) error {
+	if err := checkPin(port, bit); err != nil {
+		return err
+	}
+
+	m.schedulePin(cycle, port, 1<<bit, high)
+	return nil
+}
+
+func (m *Machine) schedulePin(cycle uint64, port int, mask byte, high bool) {
+	m.pinEvents = append(m.pinEvents, pinEvent{Cycle: cycle, Port: port, Mask: mask, High: high})
+
+	// stable, so events for the same cycle apply in the order given
+	slices.SortStableFunc(m.pinEvents, func(a, b pinEvent) int {
+		if a.Cycle < b.Cycle {
+			return -1
+		} else if a.Cycle > b.Cycle {
+			return 1
+		}
+		return 0
+	})
+}
+
+func (m *Machine) applyPinEvents() {
+	applied := 0
+	for _, ev := range m.pinEvents {
+		if ev.Cycle > m.cycles {
+			break
+		}
+
+		m.drivePins(ev.Port, ev.Mask, ev.High)
+		applied++
+	}
+
+	m.pinEvents = m.pinEvents[applied:]
+}
+
+// Samples INT0/INT1 once per machine cycle. With ITx set a high-to-low
+// transition between two samples latches IEx until the interrupt is
+// vectored, otherwise IEx simply follows the (inverted) pin level
+func (m *Machine) sampleExternalInterrupts() {
+	int0 := m.p3Pin(P3_INT0_MASK)
+	int1 := m.p3Pin(P3_INT1_MASK)
+
+	m.Data[SFR_TCON] = externalRequest(m.Data[SFR_TCON], TCON_IT0_MASK, TCON_IE0_MASK, m.int0Level, int0)
+	m.Data[SFR_TCON] = externalRequest(m.Data[SFR_TCON], TCON_IT1_MASK, TCON_IE1_MASK, m.int1Level, int1)
+
+	m.int0Level = int0
+	m.int1Level = int1
+}
+
+func externalRequest(tcon byte, it byte, ie byte, wasHigh bool, high bool) byte {
+	if tcon&it != 0 {
+		if wasHigh && !high {
+			tcon |= ie
+		}
+		return tcon
+	}
+
+	if high {
+		return tcon & ^ie
+	}
+	return tcon | ie
+}
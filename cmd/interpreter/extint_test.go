@@ -0,0 +1,161 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestExternalInterruptEdge(t *testing.T) {
+	cases := []struct {
+		Name string
+		IT   byte
+		IE   byte
+		Set  func(vm *Machine, high bool)
+	}{
+		{Name: "INT0", IT: TCON_IT0_MASK, IE: TCON_IE0_MASK, Set: (*Machine).SetINT0},
+		{Name: "INT1", IT: TCON_IT1_MASK, IE: TCON_IE1_MASK, Set: (*Machine).SetINT1},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_TCON, tc.IT)
+
+		feedNops(t, vm, 1)
+		if vm.TCON()&tc.IE != 0 {
+			t.Errorf("%s: expected no request while the pin is high", tc.Name)
+		}
+
+		tc.Set(vm, false)
+		feedNops(t, vm, 1)
+		if vm.TCON()&tc.IE == 0 {
+			t.Errorf("%s: expected falling edge to set the request flag", tc.Name)
+		}
+
+		// the request is latched until the interrupt is taken
+		tc.Set(vm, true)
+		feedNops(t, vm, 2)
+		if vm.TCON()&tc.IE == 0 {
+			t.Errorf("%s: expected request flag to stay latched", tc.Name)
+		}
+
+		// holding the pin low is not another edge
+		vm.Data[SFR_TCON] &= ^tc.IE
+		tc.Set(vm, false)
+		feedNops(t, vm, 1)
+		vm.Data[SFR_TCON] &= ^tc.IE
+		feedNops(t, vm, 3)
+		if vm.TCON()&tc.IE != 0 {
+			t.Errorf("%s: expected a held-low pin not to retrigger", tc.Name)
+		}
+	}
+}
+
+func TestExternalInterruptLevel(t *testing.T) {
+	vm := NewMachine()
+
+	vm.SetINT1(false)
+	feedNops(t, vm, 1)
+	if vm.TCON()&TCON_IE1_MASK == 0 {
+		t.Errorf("expected IE1 to be set while INT1 is low")
+	}
+
+	vm.SetINT1(true)
+	feedNops(t, vm, 1)
+	if vm.TCON()&TCON_IE1_MASK != 0 {
+		t.Errorf("expected IE1 to follow INT1 back high")
+	}
+
+	// the port latch pulls the pin low just as well, CLR P3.3
+	if err := vm.Feed([]byte{0xC2, 0xB3}); err != nil {
+		t.Fatal(err)
+	}
+	if vm.TCON()&TCON_IE1_MASK == 0 {
+		t.Errorf("expected IE1 to be set with the P3.3 latch cleared")
+	}
+}
+
+func TestLevelInterruptReentry(t *testing.T) {
+	program := make([]byte, 0x40)
+	copy(program[0x03:], []byte{
+		0x05, 0x30, // INC 30h
+		0x32, // RETI
+	})
+	copy(program[0x30:], []byte{
+		0x00,       // NOP
+		0x80, 0xFD, // SJMP 30h
+	})
+
+	vm := NewMachine()
+	vm.Load(program)
+	vm.PC = 0x30
+	vm.WriteMem(SFR_IE, IE_EA_MASK|IE_EX0_MASK)
+	vm.SetINT0(false)
+
+	// NOP -> ISR (INC, RETI) -> one main instruction -> ISR again
+	if _, err := vm.Run(7); err != nil {
+		t.Fatal(err)
+	}
+
+	count, _ := vm.ReadMem(0x30)
+	if count != 2 {
+		t.Errorf("expected a held-low level interrupt to be serviced twice, got %d", count)
+	}
+
+	// released pin, no more requests
+	vm.SetINT0(true)
+	vm.Run(20)
+
+	count, _ = vm.ReadMem(0x30)
+	if count > 3 {
+		t.Errorf("expected requests to stop once INT0 is released, got %d", count)
+	}
+}
+
+func TestScheduledButtonBounce(t *testing.T) {
+	program := make([]byte, 0x40)
+	copy(program[0x03:], []byte{
+		0x05, 0x30, // INC 30h
+		0x32, // RETI
+	})
+	copy(program[0x30:], []byte{
+		0xD2, 0x88, // SETB IT0
+		0x75, SFR_IE, 0x81, // MOV IE,#81h
+		0x80, 0xFE, // SJMP $
+	})
+
+	vm := NewMachine()
+	vm.Load(program)
+	vm.PC = 0x30
+
+	// a press that bounces three times, then a clean release
+	press := []struct {
+		Cycle uint64
+		High  bool
+	}{
+		{Cycle: 20, High: false},
+		{Cycle: 40, High: true},
+		{Cycle: 60, High: false},
+		{Cycle: 80, High: true},
+		{Cycle: 100, High: false},
+		{Cycle: 200, High: true},
+	}
+
+	// queued out of order on purpose
+	for i := len(press) - 1; i >= 0; i-- {
+		vm.ScheduleINT0(press[i].Cycle, press[i].High)
+	}
+
+	for vm.Cycles() < 300 {
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, _ := vm.ReadMem(0x30)
+	if count != 3 {
+		t.Errorf("expected one interrupt per falling edge (3), got %d", count)
+	}
+
+	if len(vm.pinEvents) != 0 {
+		t.Errorf("expected every scheduled event to be applied, %d left", len(vm.pinEvents))
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// IE bits
+const IE_EA_MASK byte = (1 << 7)
+const IE_ET2_MASK byte = (1 << 5)
+const IE_ES_MASK byte = (1 << 4)
+const IE_ET1_MASK byte = (1 << 3)
+const IE_EX1_MASK byte = (1 << 2)
+const IE_ET0_MASK byte = (1 << 1)
+const IE_EX0_MASK byte = (1 << 0)
+
+// IP bits
+const IP_PT2_MASK byte = (1 << 5)
+const IP_PS_MASK byte = (1 << 4)
+const IP_PT1_MASK byte = (1 << 3)
+const IP_PX1_MASK byte = (1 << 2)
+const IP_PT0_MASK byte = (1 << 1)
+const IP_PX0_MASK byte = (1 << 0)
+
+// Taking an interrupt is a hardware LCALL to the vector
+const INTERRUPT_OPCODE byte = 0x12
+
+type InterruptSource struct {
+	Name     string
+	Vector   uint16
+	Enable   byte // bit in IE
+	Priority byte // bit in IP
+
+	// whether the source is requesting service
+	Pending func(m *Machine) bool
+
+	// called when the interrupt is vectored, clears the
+	// flags that hardware clears on its own
+	Acknowledge func(m *Machine)
+}
+
+// Interrupt sources in their fixed polling order, which decides
+// between requests of the same priority level
+var INTERRUPTS_8051 = []InterruptSource{
+	{
+		Name: "INT0", Vector: 0x0003, Enable: IE_EX0_MASK, Priority: IP_PX0_MASK,
+		Pending: func(m *Machine) bool { return m.Data[SFR_TCON]&TCON_IE0_MASK != 0 },
+		Acknowledge: func(m *Machine) {
+			// only an edge-triggered request is cleared, a level stays for as long as the pin is low
+			if m.Data[SFR_TCON]&TCON_IT0_MASK != 0 {
+				m.Data[SFR_TCON] &= ^TCON_IE0_MASK
+			}
+		},
+	},
+	{
+		Name: "TIMER0", Vector: 0x000B, Enable: IE_ET0_MASK, Priority: IP_PT0_MASK,
+		Pending:     func(m *Machine) bool { return m.Data[SFR_TCON]&TCON_TF0_MASK != 0 },
+		Acknowledge: func(m *Machine) { m.Data[SFR_TCON] &= ^TCON_TF0_MASK },
+	},
+	{
+		Name: "INT1", Vector: 0x0013, Enable: IE_EX1_MASK, Priority: IP_PX1_MASK,
+		Pending: func(m *Machine) bool { return m.Data[SFR_TCON]&TCON_IE1_MASK != 0 },
+		Acknowledge: func(m *Machine) {
+			if m.Data[SFR_TCON]&TCON_IT1_MASK != 0 {
+				m.Data[SFR_TCON] &= ^TCON_IE1_MASK
+			}
+		},
+	},
+	{
+		Name: "TIMER1", Vector: 0x001B, Enable: IE_ET1_MASK, Priority: IP_PT1_MASK,
+		Pending:     func(m *Machine) bool { return m.Data[SFR_TCON]&TCON_TF1_MASK != 0 },
+		Acknowledge: func(m *Machine) { m.Data[SFR_TCON] &= ^TCON_TF1_MASK },
+	},
+	{
+		// RI and TI are left for the service routine to clear
+		Name: "SERIAL", Vector: 0x0023, Enable: IE_ES_MASK, Priority: IP_PS_MASK,
+		Pending:     func(m *Machine) bool { return m.Data[SFR_SCON]&(SCON_RI_MASK|SCON_TI_MASK) != 0 },
+		Acknowledge: func(m *Machine) {},
+	},
+}
+
+var INTERRUPTS_8052 = append(slices.Clone(INTERRUPTS_8051), InterruptSource{
+	// TF2 and EXF2 are left for the service routine, which has to tell them apart
+	Name: "TIMER2", Vector: 0x002B, Enable: IE_ET2_MASK, Priority: IP_PT2_MASK,
+	Pending:     func(m *Machine) bool { return m.timer2Pending() },
+	Acknowledge: func(m *Machine) {},
+})
+
+// Whether any interrupt could still be taken, i.e. EA and at
+// least one source are enabled
+func (m *Machine) interruptsEnabled() bool {
+	ie := m.Data[SFR_IE]
+	if ie&IE_EA_MASK == 0 {
+		return false
+	}
+
+	for _, src := range m.interrupts {
+		if ie&src.Enable != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Samples the interrupt sources once the current instruction is done and
+// vectors to the highest priority pending one, if any may be taken.
+//
+// @return bool - whether an interrupt was taken
+func (m *Machine) pollInterrupts() (bool, error) {
+	// RETI and writes to IE/IP always let one more instruction run
+	if m.holdInterrupts {
+		return false, nil
+	}
+
+	ie := m.Data[SFR_IE]
+	ip := m.Data[SFR_IP]
+
+	// nothing interrupts a high priority service routine
+	if ie&IE_EA_MASK == 0 || m.inService&INT_LEVEL_HIGH != 0 {
+		return false, nil
+	}
+
+	for _, level := range []byte{INT_LEVEL_HIGH, INT_LEVEL_LOW} {
+		// a low priority routine only gives way to high priority requests
+		if level == INT_LEVEL_LOW && m.inService&INT_LEVEL_LOW != 0 {
+			break
+		}
+
+		for _, src := range m.interrupts {
+			if ie&src.Enable == 0 || !src.Pending(m) {
+				continue
+			}
+
+			if (ip&src.Priority != 0) != (level == INT_LEVEL_HIGH) {
+				continue
+			}
+
+			return true, m.vectorInterrupt(src, level)
+		}
+	}
+
+	return false, nil
+}
+
+func (m *Machine) vectorInterrupt(src InterruptSource, level byte) error {
+	log.Printf("interrupt %s at %#04x, vectoring to %#04x", src.Name, m.PC, src.Vector)
+
+	if err := m.pushPC(); err != nil {
+		return err
+	}
+
+	m.PC = src.Vector
+	m.inService |= level
+	src.Acknowledge(m)
+
+	// an interrupt is the only way out of idle mode short of a reset
+	m.Data[SFR_PCON] &= ^PCON_IDL_MASK
+
+	m.tick(m.instructionCycles(INTERRUPT_OPCODE, OPCODES[INTERRUPT_OPCODE]))
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"testing"
+)
+
+// 64 NOPs, so Step has something to execute anywhere near the vectors
+func nopProgram(t *testing.T) *Machine {
+	t.Helper()
+
+	vm := NewMachine()
+	if err := vm.Load(make([]byte, 0x40)); err != nil {
+		t.Fatal(err)
+	}
+
+	return vm
+}
+
+func TestInterruptVectoring(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Enable  byte
+		Flag    func(vm *Machine)
+		Vector  uint16
+		Cleared func(vm *Machine) bool
+	}{
+		{
+			Name: "INT0 edge", Enable: IE_EX0_MASK, Vector: 0x0003,
+			Flag:    func(vm *Machine) { vm.Data[SFR_TCON] |= TCON_IT0_MASK | TCON_IE0_MASK },
+			Cleared: func(vm *Machine) bool { return vm.TCON()&TCON_IE0_MASK == 0 },
+		},
+		{
+			Name: "INT0 level", Enable: IE_EX0_MASK, Vector: 0x0003,
+			Flag:    func(vm *Machine) { vm.SetINT0(false) },
+			Cleared: func(vm *Machine) bool { return vm.TCON()&TCON_IE0_MASK != 0 }, // pin is still low
+		},
+		{
+			Name: "TIMER0", Enable: IE_ET0_MASK, Vector: 0x000B,
+			Flag:    func(vm *Machine) { vm.Data[SFR_TCON] |= TCON_TF0_MASK },
+			Cleared: func(vm *Machine) bool { return vm.TCON()&TCON_TF0_MASK == 0 },
+		},
+		{
+			Name: "INT1 edge", Enable: IE_EX1_MASK, Vector: 0x0013,
+			Flag:    func(vm *Machine) { vm.Data[SFR_TCON] |= TCON_IT1_MASK | TCON_IE1_MASK },
+			Cleared: func(vm *Machine) bool { return vm.TCON()&TCON_IE1_MASK == 0 },
+		},
+		{
+			Name: "TIMER1", Enable: IE_ET1_MASK, Vector: 0x001B,
+			Flag:    func(vm *Machine) { vm.Data[SFR_TCON] |= TCON_TF1_MASK },
+			Cleared: func(vm *Machine) bool { return vm.TCON()&TCON_TF1_MASK == 0 },
+		},
+		{
+			Name: "SERIAL", Enable: IE_ES_MASK, Vector: 0x0023,
+			Flag:    func(vm *Machine) { vm.Data[SFR_SCON] |= SCON_TI_MASK },
+			Cleared: func(vm *Machine) bool { return vm.SCON()&SCON_TI_MASK != 0 }, // left for the ISR
+		},
+	}
+
+	for _, tc := range cases {
+		vm := nopProgram(t)
+		vm.WriteMem(SFR_IE, IE_EA_MASK|tc.Enable)
+		tc.Flag(vm)
+
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.Vector {
+			t.Errorf("%s: expected PC to be %#04x, got %#04x", tc.Name, tc.Vector, vm.PC)
+		}
+
+		if vm.SP() != 0x09 {
+			t.Errorf("%s: expected PC to be pushed (SP 0x09), got SP %#02x", tc.Name, vm.SP())
+		}
+
+		lo, _ := vm.ReadIndirect(0x08)
+		hi, _ := vm.ReadIndirect(0x09)
+		if lo != 0x01 || hi != 0x00 {
+			t.Errorf("%s: expected return address 0x0001 on the stack, got %02X%02X", tc.Name, hi, lo)
+		}
+
+		if !tc.Cleared(vm) {
+			t.Errorf("%s: unexpected request flag state after vectoring, TCON=%#08b SCON=%#08b", tc.Name, vm.TCON(), vm.SCON())
+		}
+
+		if vm.inService != INT_LEVEL_LOW {
+			t.Errorf("%s: expected low priority level in service, got %d", tc.Name, vm.inService)
+		}
+	}
+}
+
+func TestInterruptDisabled(t *testing.T) {
+	cases := []struct {
+		Name string
+		IE   byte
+	}{
+		{Name: "EA clear", IE: IE_ET0_MASK},
+		{Name: "source disabled", IE: IE_EA_MASK | IE_ET1_MASK},
+	}
+
+	for _, tc := range cases {
+		vm := nopProgram(t)
+		vm.WriteMem(SFR_IE, tc.IE)
+		vm.Data[SFR_TCON] |= TCON_TF0_MASK
+
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != 0x0001 {
+			t.Errorf("%s: expected no interrupt, PC is %#04x", tc.Name, vm.PC)
+		}
+	}
+}
+
+func TestInterruptPollingOrder(t *testing.T) {
+	cases := []struct {
+		Name   string
+		IP     byte
+		Vector uint16
+	}{
+		{Name: "same priority", IP: 0x00, Vector: 0x000B},
+		{Name: "INT1 high priority", IP: IP_PX1_MASK, Vector: 0x0013},
+		{Name: "both high priority", IP: IP_PX1_MASK | IP_PT0_MASK, Vector: 0x000B},
+	}
+
+	for _, tc := range cases {
+		vm := nopProgram(t)
+		vm.WriteMem(SFR_IE, IE_EA_MASK|IE_ET0_MASK|IE_EX1_MASK|IE_ET1_MASK)
+		vm.WriteMem(SFR_IP, tc.IP)
+		vm.Data[SFR_TCON] |= TCON_TF1_MASK | TCON_IT1_MASK | TCON_IE1_MASK | TCON_TF0_MASK
+
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.Vector {
+			t.Errorf("%s: expected PC to be %#04x, got %#04x", tc.Name, tc.Vector, vm.PC)
+		}
+	}
+}
+
+func TestInterruptNesting(t *testing.T) {
+	vm := nopProgram(t)
+	vm.Code.Load(append(make([]byte, 0x30), 0x32)) // RETI at 0x30
+	vm.WriteMem(SFR_IE, IE_EA_MASK|IE_ET0_MASK|IE_ET1_MASK|IE_EX0_MASK)
+	vm.WriteMem(SFR_IP, IP_PT1_MASK)
+
+	// low priority timer 0 gets in first
+	vm.Data[SFR_TCON] |= TCON_TF0_MASK
+	vm.Step()
+	if vm.PC != 0x000B {
+		t.Fatalf("expected timer 0 interrupt, PC is %#04x", vm.PC)
+	}
+
+	// same level request waits
+	vm.Data[SFR_TCON] |= TCON_IT0_MASK | TCON_IE0_MASK
+	vm.Step()
+	if vm.PC != 0x000C {
+		t.Fatalf("expected INT0 to wait for the low priority routine, PC is %#04x", vm.PC)
+	}
+
+	// high priority pre-empts
+	vm.Data[SFR_TCON] |= TCON_TF1_MASK
+	vm.Step()
+	if vm.PC != 0x001B || vm.inService != INT_LEVEL_LOW|INT_LEVEL_HIGH {
+		t.Fatalf("expected timer 1 to pre-empt, PC is %#04x, in service %d", vm.PC, vm.inService)
+	}
+
+	// and is not pre-empted by anything
+	vm.Data[SFR_TCON] |= TCON_TF0_MASK
+	vm.Step()
+	if vm.PC != 0x001C {
+		t.Fatalf("expected high priority routine to keep running, PC is %#04x", vm.PC)
+	}
+
+	// return from the high priority routine back into the low one
+	vm.PC = 0x0030
+	vm.Step()
+	if vm.PC != 0x000D || vm.inService != INT_LEVEL_LOW {
+		t.Fatalf("expected RETI back to 0x000d at low level, PC is %#04x, in service %d", vm.PC, vm.inService)
+	}
+
+	// RETI from the low priority routine, one more instruction always runs
+	// before the pending INT0/timer 0 are taken
+	vm.PC = 0x0030
+	vm.Step()
+	if vm.PC != 0x0001 || vm.inService != 0 {
+		t.Fatalf("expected RETI back to 0x0001, PC is %#04x, in service %d", vm.PC, vm.inService)
+	}
+
+	vm.Step()
+	if vm.PC != 0x0003 {
+		t.Errorf("expected pending INT0 to be taken after one instruction, PC is %#04x", vm.PC)
+	}
+}
+
+func TestInterruptAfterIEWrite(t *testing.T) {
+	vm := nopProgram(t)
+	vm.Data[SFR_TCON] |= TCON_TF0_MASK
+
+	// MOV IE,#82h
+	if err := vm.Feed([]byte{0x75, SFR_IE, IE_EA_MASK | IE_ET0_MASK}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x0003 {
+		t.Errorf("expected no interrupt right after writing IE, PC is %#04x", vm.PC)
+	}
+
+	vm.Step()
+	if vm.PC != 0x000B {
+		t.Errorf("expected timer 0 interrupt one instruction later, PC is %#04x", vm.PC)
+	}
+}
+
+func TestTimerInterruptProgram(t *testing.T) {
+	program := make([]byte, 0x40)
+	copy(program[0x00:], []byte{0x02, 0x00, 0x30}) // LJMP main
+	copy(program[0x0B:], []byte{
+		0x05, 0x30, // INC 30h
+		0x32, // RETI
+	})
+	copy(program[0x30:], []byte{
+		0x75, SFR_TMOD, 0x02, // MOV TMOD,#02h    timer 0 mode 2
+		0x75, SFR_TH0, 0xF6, // MOV TH0,#-10
+		0x75, SFR_TL0, 0xF6, // MOV TL0,#-10
+		0x75, SFR_IE, 0x82, // MOV IE,#82h
+		0xD2, 0x8C, // SETB TR0
+		0x80, 0xFE, // SJMP $
+	})
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	executed, err := vm.Run(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 200 {
+		t.Errorf("expected SJMP $ to keep running with interrupts enabled, stopped after %d", executed)
+	}
+
+	// the timer starts 10 cycles in (LJMP + 4 MOVs) and overflows every
+	// 10 cycles, the ISR round trip is well under that. The last request
+	// may still be waiting for its INC
+	ticks, _ := vm.ReadMem(0x30)
+	expected := byte((vm.Cycles() - 10) / 10)
+	if ticks < expected-1 || ticks > expected {
+		t.Errorf("expected about %d timer interrupts in %d cycles, got %d", expected, vm.Cycles(), ticks)
+	}
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/bits"
+	"os"
 )
 
 /** Special function registers - 80h - FFh */
+// Read-only snapshot of the SFRs, see Machine.Registers.
+// The SFRs themselves only live in Machine.Data
 type Register struct {
 	ACC  byte // A Register (for arithmetic) E0H
 	B    byte // B Register (for arithmetic) F0H
@@ -28,6 +32,13 @@ type Register struct {
 	TH0  byte // Timer 0 HIGH Byte 8CH
 	TL1  byte // Timer 1 LOW Byte 8BH
 	TH1  byte // Timer 1 HIGH Byte 8DH
+
+	T2CON  byte // Timer 2 Control C8H
+	T2MOD  byte // Timer 2 Mode C9H
+	RCAP2L byte // Timer 2 Capture/Reload LOW Byte CAH
+	RCAP2H byte // Timer 2 Capture/Reload HIGH Byte CBH
+	TL2    byte // Timer 2 LOW Byte CCH
+	TH2    byte // Timer 2 HIGH Byte CDH
 }
 
 const PSW_C_MASK byte = (1 << 7)
@@ -107,6 +118,26 @@ const SFR_TH0 uint8 = 0x8C
 const SFR_TL1 uint8 = 0x8B
 const SFR_TH1 uint8 = 0x8D
 
+// 8052 timer 2
+const SFR_T2CON uint8 = 0xC8
+const SFR_T2MOD uint8 = 0xC9
+const SFR_RCAP2L uint8 = 0xCA
+const SFR_RCAP2H uint8 = 0xCB
+const SFR_TL2 uint8 = 0xCC
+const SFR_TH2 uint8 = 0xCD
+
+// Bit addresses of the PSW flags
+const BIT_CY uint8 = 0xD7
+const BIT_AC uint8 = 0xD6
+const BIT_F0 uint8 = 0xD5
+const BIT_RS1 uint8 = 0xD4
+const BIT_RS0 uint8 = 0xD3
+const BIT_OV uint8 = 0xD2
+const BIT_P uint8 = 0xD0
+
+// start of the bit-addressable area of IRAM (bits 0x00-0x7F)
+const LOC_BIT_AREA uint8 = 0x20
+
 const LOC_R0 uint8 = 0
 const LOC_R1 uint8 = 1
 const LOC_R2 uint8 = 2
@@ -118,70 +149,295 @@ const LOC_R7 uint8 = 7
 
 // Why uint16: https://stackoverflow.com/questions/57535586/why-does-the-program-counter-in-8051-is-16-bit-and-stack-pointer-is-8-bit-in-805
 type Machine struct {
-	registers Register
-	Program   []byte
-	Data      []byte
-	PC        uint16 // Program counter / instruction pointer
-	SP        uint8  // Stack pointer
+	Code      *CodeMemory
+	XRAM      *ExternalMemory
+	XBus      *XDataBus // devices and extra RAM mapped over XRAM
+	Data      []byte    // direct address space: lower 128B RAM + SFRs
+	upper     []byte    // upper 128B of IRAM, indirect only (nil on 128B parts)
+	PC        uint16    // Program counter / instruction pointer
+	inService byte      // interrupt priority levels currently being serviced
+
+	holdInterrupts bool // last instruction was RETI or wrote IE/IP
+
+	StackGuard StackGuard // stack faults to report, none by default
+	stackBase  uint8      // SP as last set by the program, bottom of the stack
+
+	ClockHz        int    // crystal frequency
+	ClocksPerCycle int    // oscillator periods per machine cycle
+	cycles         uint64 // machine cycles executed since power-on reset
+
+	t0Level bool // T0 pin level at the last sample, for edge counting
+	t1Level bool // T1 pin level at the last sample, for edge counting
+
+	// oscillator periods towards the next count of each timer
+	t0Clocks int
+	t1Clocks int
+	t2Clocks int
+
+	t2Level   bool // T2 pin level at the last sample, for edge counting
+	t2exLevel bool // T2EX pin level at the last sample, for capture/reload
+
+	int0Level bool       // INT0 pin level at the last sample, for edge triggering
+	int1Level bool       // INT1 pin level at the last sample, for edge triggering
+	pinEvents []pinEvent // scheduled pin changes, in cycle order
+
+	serial serialPort
+
+	ports       [PORT_COUNT]portDrive // what the outside world drives onto the pins
+	pinWatchers []PinChangeFunc
+
+	device     Device
+	sfrs       [256]bool // SFR addresses the device implements
+	interrupts []InterruptSource
+
+	peripherals []Peripheral
+	sfrOwners   [256]Peripheral // peripheral owning each SFR address, if any
 }
 
+// On 8052-class parts the upper 128B of IRAM (0x80-0xFF) share their
+// addresses with the SFRs, indirect addressing reaches the RAM while
+// direct addressing reaches the SFRs
+const LOC_UPPER_RAM uint8 = 0x80
+const IRAM_UPPER_SIZE int = 128
+
+// Interrupt priority levels, as tracked in Machine.inService
+const INT_LEVEL_LOW byte = (1 << 0)
+const INT_LEVEL_HIGH byte = (1 << 1)
+
+// A machine that can run anything the interpreter models,
+// see NewMachineFor to check firmware against a particular part
 func NewMachine() *Machine {
-	vm := Machine{
-		registers: Register{},
-		Program:   make([]byte, 4*1024, 4*1024), // pre-allocate 4KB ROM
-		Data:      make([]byte, 256, 256),       // pre-allocate 256B RAM
-		PC:        0,
-		SP:        LOC_R7, // Stack starts at 0x07
+	vm, _ := NewMachineFor(DEVICE_GENERIC)
+
+	return vm
+}
+
+// SFR values after reset, from the datasheet. SFRs not listed
+// (and unimplemented bits) come out of reset as 0
+var SFR_RESET_VALUES = map[uint8]byte{
+	SFR_P0: 0xFF,
+	SFR_P1: 0xFF,
+	SFR_P2: 0xFF,
+	SFR_P3: 0xFF,
+	SFR_SP: LOC_R7, // Stack starts at 0x07
+}
+
+// Power-on reset: internal RAM is cleared and every SFR
+// takes its reset value
+func (m *Machine) Reset() {
+	clear(m.Data)
+	clear(m.upper)
+	m.cycles = 0
+
+	m.WarmReset()
+}
+
+// Reset with the supply held up (RST pin pulse or watchdog):
+// internal RAM keeps its contents, and so does SBUF, which
+// the datasheet lists as indeterminate after reset
+func (m *Machine) WarmReset() {
+	for loc := int(LOC_UPPER_RAM); loc < len(m.Data); loc++ {
+		if uint8(loc) != SFR_SBUF {
+			m.Data[loc] = 0
+		}
 	}
 
-	return &vm
+	for loc, value := range SFR_RESET_VALUES {
+		m.WriteMem(loc, value)
+	}
+	m.updateParity()
+
+	for loc, value := range m.device.ResetValues {
+		m.WriteMem(loc, value)
+	}
+
+	m.PC = 0
+	m.inService = 0
+	m.holdInterrupts = false
+
+	m.t0Clocks = 0
+	m.t1Clocks = 0
+	m.t2Clocks = 0
+
+	// abandon any frame being shifted in or out
+	m.serial.tx = serialShift{}
+	m.serial.rx = serialShift{}
+
+	m.resetPeripherals()
 }
 
 type EvalOperation func(vm *Machine, operands []byte) error
 
 type Opcode struct {
-	Name string
-	Eval EvalOperation
+	Name   string
+	Size   int // opcode + operands, in bytes
+	Cycles int // machine cycles taken to execute
+	Eval   EvalOperation
 }
 
 var OPCODES map[byte]Opcode = operationTable()
 
+// Load copies a firmware image into program memory starting at 0x0000
+// and points PC at the first instruction
+func (m *Machine) Load(program []byte) error {
+	if err := m.Code.Load(program); err != nil {
+		return err
+	}
+
+	m.PC = 0
+	return nil
+}
+
 // []byte{op, oper1, oper2}
 func (m *Machine) Feed(instructions []byte) error {
 	if len(instructions) < 1 {
 		return fmt.Errorf("instruction must be at least 1 byte")
 	}
 
-	opcode := instructions[0]
-	operands := instructions[1:]
+	return m.execute(instructions[0], instructions[1:])
+}
+
+// Step fetches the instruction at PC from program memory,
+// decodes its operands and executes it. In idle mode it only
+// advances the machine by one cycle, in power down it does nothing
+func (m *Machine) Step() error {
+	switch m.PowerState() {
+	case POWER_DOWN:
+		return nil
+	case POWER_IDLE:
+		return m.idleCycle()
+	}
+
+	opcode, err := m.Code.Read(m.PC)
+	if err != nil {
+		return fmt.Errorf("instruction fetch failed: %s", err)
+	}
+
+	op, ok := OPCODES[opcode]
+	if !ok {
+		return fmt.Errorf("opcode '%02x' does not exist in OPCODES", opcode)
+	}
+
+	operands := make([]byte, op.Size-1)
+	for i := range operands {
+		operands[i], err = m.Code.Read(m.PC + uint16(i) + 1)
+		if err != nil {
+			return fmt.Errorf("operand fetch for '%02X' failed: %s", opcode, err)
+		}
+	}
+
+	return m.execute(opcode, operands)
+}
+
+// Run keeps stepping until the machine halts or limit instructions have
+// been executed (limit <= 0 means no limit). There is no halt instruction
+// on the 8051, so the machine is considered halted when PC leaves the
+// loaded image or an unconditional jump targets itself (e.g. SJMP $)
+// while no interrupt is enabled. Likewise it stops in power down mode,
+// and in idle mode when there is no interrupt left to wake it.
+//
+// @return int - number of instructions executed, each machine cycle
+// spent idle counts as one
+func (m *Machine) Run(limit int) (int, error) {
+	executed := 0
+
+	for limit <= 0 || executed < limit {
+		switch m.PowerState() {
+		case POWER_DOWN:
+			return executed, nil
+		case POWER_IDLE:
+			if !m.interruptsEnabled() {
+				return executed, nil
+			}
+
+			if err := m.idleCycle(); err != nil {
+				return executed, fmt.Errorf("idle at %#04x: %w", m.PC, err)
+			}
+			executed++
+			continue
+		}
+
+		if int(m.PC) >= m.Code.Size() {
+			break
+		}
+
+		pc := m.PC
+		opcode, _ := m.Code.Read(pc)
+		if err := m.Step(); err != nil {
+			return executed, fmt.Errorf("at %#04x: %w", pc, err)
+		}
+		executed++
+
+		// DJNZ Rn,$ and friends also land on themselves but
+		// eventually fall through, so only plain jumps count.
+		// With interrupts enabled SJMP $ is just waiting for one
+		if m.PC == pc && isUnconditionalJump(opcode) && !m.interruptsEnabled() {
+			break
+		}
+	}
+
+	return executed, nil
+}
+
+// Machine cycles op takes on this device
+func (m *Machine) instructionCycles(opcode byte, op Opcode) int {
+	if m.device.InstructionCycles == nil {
+		return op.Cycles
+	}
+
+	return m.device.InstructionCycles(opcode, op)
+}
+
+// AJMP, LJMP, SJMP, JMP @A+DPTR
+func isUnconditionalJump(opcode byte) bool {
+	return opcode&0x1F == 0x01 || opcode == 0x02 || opcode == 0x80 || opcode == 0x73
+}
 
+func (m *Machine) execute(opcode byte, operands []byte) error {
 	op, ok := OPCODES[opcode]
 	if !ok {
 		return fmt.Errorf("opcode '%02x' does not exist in OPCODES", opcode)
 	}
 
-	// we know that the instruction will be 3 bytes at most
-	// so we can cast the length to uint16
-	if m.PC > uint16(0xFFFF)-uint16(len(instructions)) {
-		return fmt.Errorf("cannot execute instruction because program counter exceeds 0xFFFF (65535)")
+	if len(operands) < op.Size-1 {
+		return fmt.Errorf("opcode '%02X' (%s) expects %d operand(s), got %d", opcode, op.Name, op.Size-1, len(operands))
 	}
+	operands = operands[:op.Size-1]
 
 	log.Printf("executing instruction '%02X' (%s) with operand '%v' (bank %d)", opcode, op.Name, operands, m.bankNo())
 
-	log.Printf("BEFORE: %+v\n", m.registers)
+	log.Printf("BEFORE: %+v\n", m.Registers())
+
+	// like the real CPU, PC already points at the next instruction while
+	// the operation executes, so jumps and branches just overwrite it.
+	// Past 0xFFFF it wraps around to 0x0000
+	pc := m.PC
+	m.PC += uint16(op.Size)
+	m.holdInterrupts = false
 
 	evalErr := op.Eval(m, operands)
 	if evalErr != nil {
-		return fmt.Errorf("VM eval error: %s", evalErr)
+		m.PC = pc
+		return fmt.Errorf("VM eval error: %w", evalErr)
 	}
 
-	log.Printf("AFTER: %+v\n", m.registers)
+	m.tick(m.instructionCycles(opcode, op))
 
-	m.PC += uint16(len(instructions))
+	if _, err := m.pollInterrupts(); err != nil {
+		return fmt.Errorf("interrupt error: %w", err)
+	}
+
+	log.Printf("AFTER: %+v\n", m.Registers())
 
 	return nil
 }
 
+// Relative jump from the address of the next instruction,
+// rel is a signed 8-bit offset (-128 to +127)
+func (m *Machine) jumpRelative(rel byte) {
+	m.PC += uint16(int16(int8(rel)))
+}
+
 // TODO: 8051 has 256B of memory
 // but technically it can be extended, so should the location be byte or int?
 func (m *Machine) WriteMem(loc uint8, value byte) error {
@@ -189,173 +445,123 @@ func (m *Machine) WriteMem(loc uint8, value byte) error {
 		return fmt.Errorf("location %#02x exceeds memory capacity of %#02x (%dB)", loc, cap(m.Data), cap(m.Data))
 	}
 
+	if p := m.sfrOwners[loc]; p != nil {
+		return p.WriteSFR(m, loc, value)
+	}
+
+	if err := m.checkSFR(loc); err != nil {
+		return err
+	}
+
+	// SBUF reads back the receive buffer, what is written goes out on TXD
+	if loc == SFR_SBUF {
+		m.serialTransmit(value)
+		return nil
+	}
+
+	if port, ok := portIndex(loc); ok {
+		m.updatePins(port, func() { m.Data[loc] = value })
+		return nil
+	}
+
 	m.Data[loc] = value
 
-	// these registers are accessible by memory, so we also have to write to it
-	// TODO: ada problem kalau write to register directly, nanti value dia tak tally dengan memory
-	// TODO: maybe kena validate value for certain registers??
 	switch loc {
 	case SFR_ACC:
-		m.registers.ACC = value
-	case SFR_B:
-		m.registers.B = value
-	case SFR_DPH:
-		m.registers.DPH = value
-	case SFR_DPL:
-		m.registers.DPL = value
-	case SFR_IE:
-		m.registers.IE = value
-	case SFR_IP:
-		m.registers.IP = value
-	case SFR_P0:
-		m.registers.P0 = value
-	case SFR_P1:
-		m.registers.P1 = value
-	case SFR_P2:
-		m.registers.P2 = value
-	case SFR_P3:
-		m.registers.P3 = value
-	case SFR_PCON:
-		m.registers.PCON = value
-	case SFR_PSW:
-		m.registers.PSW = value
-	case SFR_SCON:
-		m.registers.SCON = value
-	case SFR_SBUF:
-		m.registers.SBUF = value
+		m.updateParity()
 	case SFR_SP:
-		m.registers.SP = value
-	case SFR_TMOD:
-		m.registers.TMOD = value
-	case SFR_TCON:
-		m.registers.TCON = value
-	case SFR_TL0:
-		m.registers.TL0 = value
-	case SFR_TH0:
-		m.registers.TH0 = value
-	case SFR_TL1:
-		m.registers.TL1 = value
-	case SFR_TH1:
-		m.registers.TH1 = value
+		m.stackBase = value
+	case SFR_IE, SFR_IP:
+		m.holdInterrupts = true
+	case SFR_PSW:
+		// P is read-only, writing PSW cannot change it
+		m.updateParity()
 	}
 
 	return nil
 }
 
+// PSW.P is set by hardware whenever A holds an odd number of 1 bits
+func (m *Machine) updateParity() {
+	psw := m.Data[SFR_PSW]
+
+	if bits.OnesCount8(m.Data[SFR_ACC])%2 == 1 {
+		psw = PSW_SET(psw, PSW_P_MASK)
+	} else {
+		psw = PSW_UNSET(psw, PSW_P_MASK)
+	}
+
+	m.Data[SFR_PSW] = psw
+}
+
 func (m *Machine) ReadMem(loc uint8) (byte, error) {
-	var value byte = m.Data[loc]
+	if int(loc) >= len(m.Data) {
+		return 0, fmt.Errorf("location %#02x exceeds memory capacity of %#02x (%dB)", loc, cap(m.Data), cap(m.Data))
+	}
 
-	var registerValue byte
+	if p := m.sfrOwners[loc]; p != nil {
+		return p.ReadSFR(m, loc)
+	}
 
-	switch loc {
-	case SFR_ACC:
-		registerValue = m.registers.ACC
-		if registerValue != value {
-			fmt.Printf("ACC register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_ACC)
-		}
-	case SFR_B:
-		registerValue = m.registers.B
-		if registerValue != value {
-			fmt.Printf("B register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_B)
-		}
-	case SFR_DPH:
-		registerValue = m.registers.DPH
-		if registerValue != value {
-			fmt.Printf("DPH register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_DPH)
-		}
-	case SFR_DPL:
-		registerValue = m.registers.DPL
-		if registerValue != value {
-			fmt.Printf("DPL register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_DPL)
-		}
-	case SFR_IE:
-		registerValue = m.registers.IE
-		if registerValue != value {
-			fmt.Printf("IE register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_IE)
-		}
-	case SFR_IP:
-		registerValue = m.registers.IP
-		if registerValue != value {
-			fmt.Printf("IP register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_IP)
-		}
-	case SFR_P0:
-		registerValue = m.registers.P0
-		if registerValue != value {
-			fmt.Printf("P0 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_P0)
-		}
-	case SFR_P1:
-		registerValue = m.registers.P1
-		if registerValue != value {
-			fmt.Printf("P1 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_P1)
-		}
-	case SFR_P2:
-		registerValue = m.registers.P2
-		if registerValue != value {
-			fmt.Printf("P2 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_P2)
-		}
-	case SFR_P3:
-		registerValue = m.registers.P3
-		if registerValue != value {
-			fmt.Printf("P3 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_P3)
-		}
-	case SFR_PCON:
-		registerValue = m.registers.PCON
-		if registerValue != value {
-			fmt.Printf("PCON register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_PCON)
-		}
-	case SFR_PSW:
-		registerValue = m.registers.PSW
-		if registerValue != value {
-			fmt.Printf("PSW register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_PSW)
-		}
-	case SFR_SCON:
-		registerValue = m.registers.SCON
-		if registerValue != value {
-			fmt.Printf("SCON register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_SCON)
-		}
-	case SFR_SBUF:
-		registerValue = m.registers.SBUF
-		if registerValue != value {
-			fmt.Printf("SBUF register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_SBUF)
-		}
-	case SFR_SP:
-		registerValue = m.registers.SP
-		if registerValue != value {
-			fmt.Printf("SP register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_SP)
-		}
-	case SFR_TMOD:
-		registerValue = m.registers.TMOD
-		if registerValue != value {
-			fmt.Printf("TMOD register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TMOD)
-		}
-	case SFR_TCON:
-		registerValue = m.registers.TCON
-		if registerValue != value {
-			fmt.Printf("TCON register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TCON)
-		}
-	case SFR_TL0:
-		registerValue = m.registers.TL0
-		if registerValue != value {
-			fmt.Printf("TL0 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TL0)
-		}
-	case SFR_TH0:
-		registerValue = m.registers.TH0
-		if registerValue != value {
-			fmt.Printf("TH0 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TH0)
-		}
-	case SFR_TL1:
-		registerValue = m.registers.TL1
-		if registerValue != value {
-			fmt.Printf("TL1 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TL1)
-		}
-	case SFR_TH1:
-		registerValue = m.registers.TH1
-		if registerValue != value {
-			fmt.Printf("TH1 register value '%#02x' does not match memory at location %#02x\n", registerValue, SFR_TH1)
-		}
+	if err := m.checkSFR(loc); err != nil {
+		return 0, err
+	}
+
+	// reading a port returns what is on the pins, not the latch
+	if port, ok := portIndex(loc); ok {
+		return m.portPins(port), nil
+	}
+
+	return m.Data[loc], nil
+}
+
+// Size of the internal RAM reachable through indirect addressing,
+// 128B on the 8051 and 256B on the 8052
+func (m *Machine) IRAMSize() int {
+	return int(LOC_UPPER_RAM) + len(m.upper)
+}
+
+// Selects between the 8051 (128B) and 8052 (256B) internal RAM models
+func (m *Machine) SetIRAMSize(size int) error {
+	switch size {
+	case int(LOC_UPPER_RAM):
+		m.upper = nil
+	case int(LOC_UPPER_RAM) + IRAM_UPPER_SIZE:
+		if m.upper == nil {
+			m.upper = make([]byte, IRAM_UPPER_SIZE)
+		}
+	default:
+		return fmt.Errorf("internal RAM must be %dB or %dB, got %dB", LOC_UPPER_RAM, int(LOC_UPPER_RAM)+IRAM_UPPER_SIZE, size)
+	}
+
+	return nil
+}
+
+// Reads internal RAM the way @Ri and the stack do: 0x00-0x7F is the
+// same RAM as direct addressing, 0x80-0xFF is the upper RAM, never an SFR
+func (m *Machine) ReadIndirect(loc uint8) (byte, error) {
+	if loc < LOC_UPPER_RAM {
+		return m.ReadMem(loc)
+	}
+
+	if m.upper == nil {
+		return 0, fmt.Errorf("indirect address %#02x exceeds internal RAM of %dB", loc, m.IRAMSize())
+	}
+
+	return m.upper[loc-LOC_UPPER_RAM], nil
+}
+
+func (m *Machine) WriteIndirect(loc uint8, value byte) error {
+	if loc < LOC_UPPER_RAM {
+		return m.WriteMem(loc, value)
+	}
+
+	if m.upper == nil {
+		return fmt.Errorf("indirect address %#02x exceeds internal RAM of %dB", loc, m.IRAMSize())
 	}
 
-	return value, nil
+	m.upper[loc-LOC_UPPER_RAM] = value
+	return nil
 }
 
 func (m *Machine) DerefMem(loc uint8) (byte, error) {
@@ -364,7 +570,7 @@ func (m *Machine) DerefMem(loc uint8) (byte, error) {
 		return 0, fmt.Errorf("failed to read memory location %#02x: %s", loc, err)
 	}
 
-	val, err := m.ReadMem(ptr)
+	val, err := m.ReadIndirect(ptr)
 	if err != nil {
 		return 0, fmt.Errorf("dereference error at address %02x: %s", ptr, err)
 	}
@@ -381,11 +587,61 @@ func (m *Machine) SetrefMem(loc uint8, value byte) error {
 		return err
 	}
 
-	err = m.WriteMem(ptr, value)
+	err = m.WriteIndirect(ptr, value)
 
 	return err
 }
 
+// Maps a bit address to the address of the byte holding it and the bit mask.
+// Bits 0x00-0x7F live in IRAM 0x20-0x2F, bits 0x80-0xFF live in the
+// SFRs whose address ends in 0 or 8 (P0, TCON, P1, SCON, ..., ACC, B)
+func bitLocation(bit uint8) (uint8, byte) {
+	if bit < 0x80 {
+		return LOC_BIT_AREA + bit/8, 1 << (bit % 8)
+	}
+
+	return bit & 0xF8, 1 << (bit & 0x07)
+}
+
+func (m *Machine) ReadBit(bit uint8) (bool, error) {
+	loc, mask := bitLocation(bit)
+
+	val, err := m.ReadMem(loc)
+	if err != nil {
+		return false, fmt.Errorf("failed to read bit %#02x: %s", bit, err)
+	}
+
+	return val&mask == mask, nil
+}
+
+// Bits are written through WriteMem so that side effects of the
+// owning byte (parity, port pins) apply. Like the CPU's bit
+// instructions this reads the latch of a port, not its pins
+func (m *Machine) WriteBit(bit uint8, value bool) error {
+	loc, mask := bitLocation(bit)
+
+	val, err := m.readLatch(loc)
+	if err != nil {
+		return fmt.Errorf("failed to read bit %#02x: %s", bit, err)
+	}
+
+	if value {
+		val |= mask
+	} else {
+		val &= ^mask
+	}
+
+	return m.WriteMem(loc, val)
+}
+
+func (m *Machine) SetDPTR(value uint16) error {
+	if err := m.WriteMem(SFR_DPH, byte(value>>8)); err != nil {
+		return err
+	}
+
+	return m.WriteMem(SFR_DPL, byte(value))
+}
+
 func (m *Machine) bankNo() byte {
 	psw, _ := m.ReadMem(SFR_PSW)
 	bankNo := psw & (PSW_RS1_MASK | PSW_RS0_MASK) >> 3
@@ -434,20 +690,21 @@ func (m *Machine) SetrefBank(loc uint8, value byte) error {
 }
 
 func main() {
-	m := Machine{
-		registers: Register{},
-	}
+	m := NewMachine()
+
+	// the UART owns stdout, tracing goes to stderr through log
+	m.AttachSerial(nil, os.Stdout)
 
 	// ni kalau receive raw instruction/byte code
 	// TODO: pass assembly, software akan translate jadi byte code, feed masuk VM
 	err := m.Feed([]byte{0x24, 0xFF})
 	if err != nil {
-		fmt.Printf("err: %s\n", err)
+		fmt.Fprintf(os.Stderr, "err: %s\n", err)
 	}
 }
 
 func genericOrl(vm *Machine, dest uint8, src uint8) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -462,7 +719,7 @@ func genericOrl(vm *Machine, dest uint8, src uint8) error {
 }
 
 func genericOrlImm(vm *Machine, dest uint8, value byte) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -472,7 +729,7 @@ func genericOrlImm(vm *Machine, dest uint8, value byte) error {
 }
 
 func genericAnl(vm *Machine, dest uint8, src uint8) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -487,7 +744,7 @@ func genericAnl(vm *Machine, dest uint8, src uint8) error {
 }
 
 func genericAnlImm(vm *Machine, dest uint8, value byte) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -497,7 +754,7 @@ func genericAnlImm(vm *Machine, dest uint8, value byte) error {
 }
 
 func genericXrl(vm *Machine, dest uint8, src uint8) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -512,7 +769,7 @@ func genericXrl(vm *Machine, dest uint8, src uint8) error {
 }
 
 func genericXrlImm(vm *Machine, dest uint8, immVal byte) error {
-	destVal, err := vm.ReadMem(dest)
+	destVal, err := vm.readLatch(dest)
 	if err != nil {
 		return err
 	}
@@ -543,119 +800,369 @@ func genericXch(vm *Machine, dest uint8, src uint8) error {
 	return nil
 }
 
-func genericXchd(vm *Machine, dest uint8, src uint8) error {
-	destVal, err := vm.ReadMem(dest)
+// XCH A,@Ri: the pointer in Ri goes through indirect addressing
+func genericXchBank(vm *Machine, reg uint8) error {
+	A, err := vm.ReadMem(SFR_ACC)
 	if err != nil {
 		return err
 	}
 
-	srcVal, err := vm.ReadMem(src)
+	val, err := vm.DerefBank(reg)
 	if err != nil {
 		return err
 	}
 
-	destLower := destVal & 0b00001111
-	destUpper := destVal & 0b11110000
+	if err := vm.SetrefBank(reg, A); err != nil {
+		return err
+	}
 
-	srcLower := srcVal & 0b00001111
-	srcUpper := srcVal & 0b11110000
+	return vm.WriteMem(SFR_ACC, val)
+}
 
-	newDest := destUpper | srcLower
-	newSrc := srcUpper | destLower
+// XCHD A,@Ri: swaps only the low nibbles
+func genericXchdBank(vm *Machine, reg uint8) error {
+	A, err := vm.ReadMem(SFR_ACC)
+	if err != nil {
+		return err
+	}
 
-	if err := vm.WriteMem(src, newSrc); err != nil {
+	val, err := vm.DerefBank(reg)
+	if err != nil {
 		return err
 	}
 
-	if err := vm.WriteMem(dest, newDest); err != nil {
+	if err := vm.SetrefBank(reg, val&0b11110000|A&0b00001111); err != nil {
 		return err
 	}
 
-	return nil
+	return vm.WriteMem(SFR_ACC, A&0b11110000|val&0b00001111)
 }
 
-func operationTable() map[byte]Opcode {
-	tbl := make(map[byte]Opcode)
-	tbl[0x00] = Opcode{Name: "NOP", Eval: func(vm *Machine, operands []byte) error {
-		fmt.Println("PERFORMING NOP")
-		return nil
-	}}
-
-	tbl[0x01] = Opcode{Name: "AJMP codeaddr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+// AJMP/ACALL only carry the lower 11 bits of the target: the top 3 of
+// those are encoded in the opcode (page) and the rest in the operand.
+// The upper 5 bits come from the address of the next instruction.
+func absoluteTarget(pc uint16, page byte, addr byte) uint16 {
+	return (pc & 0xF800) | uint16(page&0b111)<<8 | uint16(addr)
+}
 
-	tbl[0x02] = Opcode{Name: "LJMP codeaddr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+func genericAjmp(vm *Machine, page byte, addr byte) error {
+	vm.PC = absoluteTarget(vm.PC, page, addr)
+	return nil
+}
 
-	tbl[0x03] = Opcode{Name: "RR A", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
+func genericAcall(vm *Machine, page byte, addr byte) error {
+	if err := vm.pushPC(); err != nil {
+		return err
+	}
 
-		lsb := A & 0b00000001
-		lsbToMsb := (lsb << 7)
-		ARightShifted := A >> 1
-		retval := lsbToMsb | ARightShifted
+	vm.PC = absoluteTarget(vm.PC, page, addr)
+	return nil
+}
 
-		err = vm.WriteMem(SFR_ACC, retval)
+// Sets the carry flag if dest < src (unsigned)
+// and jumps relative if the two are not equal
+func genericCjne(vm *Machine, dest byte, src byte, rel byte) error {
+	psw, err := vm.ReadMem(SFR_PSW)
+	if err != nil {
 		return err
-	}}
-
-	tbl[0x04] = Opcode{Name: "INC A", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
+	}
 
-		A += 1
+	if dest < src {
+		psw = PSW_SET(psw, PSW_C_MASK)
+	} else {
+		psw = PSW_UNSET(psw, PSW_C_MASK)
+	}
 
-		err = vm.WriteMem(SFR_ACC, A)
+	if err := vm.WriteMem(SFR_PSW, psw); err != nil {
 		return err
-	}}
+	}
 
-	tbl[0x05] = Opcode{Name: "INC ramaddr", Eval: func(vm *Machine, operands []byte) error {
-		addr := operands[0]
-		val, err := vm.ReadMem(addr)
-		if err != nil {
-			return err
-		}
+	if dest != src {
+		vm.jumpRelative(rel)
+	}
+	return nil
+}
 
-		val += 1
-		err = vm.WriteMem(addr, val)
+// Decrements register Rn of the current bank and jumps relative
+// if the result is not zero
+func genericDjnzBank(vm *Machine, reg uint8, rel byte) error {
+	val, err := vm.ReadBankMem(reg)
+	if err != nil {
 		return err
-	}}
-
-	tbl[0x06] = Opcode{Name: "INC @R0", Eval: func(vm *Machine, operands []byte) error {
-		val, err := vm.DerefBank(LOC_R0)
-		if err != nil {
-			return err
-		}
+	}
 
-		val += 1
-		err = vm.SetrefBank(LOC_R0, val)
+	val -= 1
+	if err := vm.WriteBankMem(reg, val); err != nil {
 		return err
-	}}
+	}
 
-	tbl[0x07] = Opcode{Name: "INC @R1", Eval: func(vm *Machine, operands []byte) error {
-		val, err := vm.DerefBank(LOC_R1)
-		if err != nil {
-			return err
-		}
+	if val != 0 {
+		vm.jumpRelative(rel)
+	}
+	return nil
+}
 
-		val += 1
-		err = vm.SetrefBank(LOC_R1, val)
+// C = C OR bit, or C = C OR NOT bit when invert is set
+func genericOrlC(vm *Machine, bit uint8, invert bool) error {
+	val, err := vm.ReadBit(bit)
+	if err != nil {
 		return err
-	}}
+	}
 
-	tbl[0x08] = Opcode{Name: "INC R0", Eval: func(vm *Machine, operands []byte) error {
-		val, err := vm.ReadBankMem(LOC_R0)
-		if err != nil {
-			return err
+	carry, err := vm.ReadBit(BIT_CY)
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteBit(BIT_CY, carry || (val != invert))
+}
+
+// C = C AND bit, or C = C AND NOT bit when invert is set
+func genericAnlC(vm *Machine, bit uint8, invert bool) error {
+	val, err := vm.ReadBit(bit)
+	if err != nil {
+		return err
+	}
+
+	carry, err := vm.ReadBit(BIT_CY)
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteBit(BIT_CY, carry && (val != invert))
+}
+
+// CPL is read-modify-write, on a port it complements the latch, not the pin
+func genericCplBit(vm *Machine, bit uint8) error {
+	val, err := vm.readLatchBit(bit)
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteBit(bit, !val)
+}
+
+// Result of an 8-bit add or subtract along with the PSW flags it produces
+type aluResult struct {
+	Value byte
+	CY    bool // carry out of (or borrow into) bit 7
+	AC    bool // carry out of (or borrow into) bit 3
+	OV    bool // signed overflow: carry/borrow on bit 6 XOR on bit 7
+}
+
+// a + b + carry
+func aluAdd(a byte, b byte, carry bool) aluResult {
+	var c int
+	if carry {
+		c = 1
+	}
+
+	sum := int(a) + int(b) + c
+	carry7 := sum > 0xFF
+	carry6 := int(a&0x7F)+int(b&0x7F)+c > 0x7F
+
+	return aluResult{
+		Value: byte(sum),
+		CY:    carry7,
+		AC:    int(a&0x0F)+int(b&0x0F)+c > 0x0F,
+		OV:    carry6 != carry7,
+	}
+}
+
+// a - b - borrow
+func aluSubb(a byte, b byte, borrow bool) aluResult {
+	var c int
+	if borrow {
+		c = 1
+	}
+
+	diff := int(a) - int(b) - c
+	borrow7 := diff < 0
+	borrow6 := int(a&0x7F)-int(b&0x7F)-c < 0
+
+	return aluResult{
+		Value: byte(diff),
+		CY:    borrow7,
+		AC:    int(a&0x0F)-int(b&0x0F)-c < 0,
+		OV:    borrow6 != borrow7,
+	}
+}
+
+// Stores the result in A and its CY, AC and OV flags in PSW
+func (m *Machine) writeAluResult(res aluResult) error {
+	psw, err := m.ReadMem(SFR_PSW)
+	if err != nil {
+		return err
+	}
+
+	flags := []struct {
+		mask byte
+		set  bool
+	}{
+		{mask: PSW_C_MASK, set: res.CY},
+		{mask: PSW_AC_MASK, set: res.AC},
+		{mask: PSW_OV_MASK, set: res.OV},
+	}
+
+	for _, flag := range flags {
+		if flag.set {
+			psw = PSW_SET(psw, flag.mask)
+		} else {
+			psw = PSW_UNSET(psw, flag.mask)
+		}
+	}
+
+	if err := m.WriteMem(SFR_PSW, psw); err != nil {
+		return err
+	}
+
+	return m.WriteMem(SFR_ACC, res.Value)
+}
+
+// ADD (withCarry false) and ADDC (withCarry true)
+func genericAdd(vm *Machine, val byte, withCarry bool) error {
+	A, err := vm.ReadMem(SFR_ACC)
+	if err != nil {
+		return err
+	}
+
+	psw, err := vm.ReadMem(SFR_PSW)
+	if err != nil {
+		return err
+	}
+
+	return vm.writeAluResult(aluAdd(A, val, withCarry && PSW_C(psw)))
+}
+
+func genericSubb(vm *Machine, val byte) error {
+	A, err := vm.ReadMem(SFR_ACC)
+	if err != nil {
+		return err
+	}
+
+	psw, err := vm.ReadMem(SFR_PSW)
+	if err != nil {
+		return err
+	}
+
+	return vm.writeAluResult(aluSubb(A, val, PSW_C(psw)))
+}
+
+// Loads A from code memory at base + A
+func genericMovc(vm *Machine, base uint16) error {
+	A, err := vm.ReadMem(SFR_ACC)
+	if err != nil {
+		return err
+	}
+
+	val, err := vm.Code.Read(base + uint16(A))
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteMem(SFR_ACC, val)
+}
+
+// A = XRAM[addr]
+func genericMovxRead(vm *Machine, addr uint16) error {
+	val, err := vm.ReadXMem(addr)
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteMem(SFR_ACC, val)
+}
+
+// XRAM[addr] = A
+func genericMovxWrite(vm *Machine, addr uint16) error {
+	A, err := vm.ReadMem(SFR_ACC)
+	if err != nil {
+		return err
+	}
+
+	return vm.WriteXMem(addr, A)
+}
+
+func operationTable() map[byte]Opcode {
+	tbl := make(map[byte]Opcode)
+	tbl[0x00] = Opcode{Name: "NOP", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return nil
+	}}
+
+	tbl[0x01] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 0, operands[0])
+	}}
+
+	tbl[0x02] = Opcode{Name: "LJMP codeaddr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		vm.PC = uint16(operands[0])<<8 | uint16(operands[1])
+		return nil
+	}}
+
+	tbl[0x03] = Opcode{Name: "RR A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
+
+		lsb := A & 0b00000001
+		lsbToMsb := (lsb << 7)
+		ARightShifted := A >> 1
+		retval := lsbToMsb | ARightShifted
+
+		err = vm.WriteMem(SFR_ACC, retval)
+		return err
+	}}
+
+	tbl[0x04] = Opcode{Name: "INC A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
+
+		A += 1
+
+		err = vm.WriteMem(SFR_ACC, A)
+		return err
+	}}
+
+	tbl[0x05] = Opcode{Name: "INC ramaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		addr := operands[0]
+		val, err := vm.readLatch(addr)
+		if err != nil {
+			return err
+		}
+
+		val += 1
+		err = vm.WriteMem(addr, val)
+		return err
+	}}
+
+	tbl[0x06] = Opcode{Name: "INC @R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		val += 1
+		err = vm.SetrefBank(LOC_R0, val)
+		return err
+	}}
+
+	tbl[0x07] = Opcode{Name: "INC @R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		val += 1
+		err = vm.SetrefBank(LOC_R1, val)
+		return err
+	}}
+
+	tbl[0x08] = Opcode{Name: "INC R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R0)
+		if err != nil {
+			return err
 		}
 
 		val += 1
@@ -663,7 +1170,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x09] = Opcode{Name: "INC R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x09] = Opcode{Name: "INC R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R1)
 		if err != nil {
 			return err
@@ -674,7 +1181,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0a] = Opcode{Name: "INC R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0a] = Opcode{Name: "INC R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R2)
 		if err != nil {
 			return err
@@ -685,7 +1192,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0b] = Opcode{Name: "INC R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0b] = Opcode{Name: "INC R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R3)
 		if err != nil {
 			return err
@@ -696,7 +1203,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0c] = Opcode{Name: "INC R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0c] = Opcode{Name: "INC R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R4)
 		if err != nil {
 			return err
@@ -707,7 +1214,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0d] = Opcode{Name: "INC R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0d] = Opcode{Name: "INC R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R5)
 		if err != nil {
 			return err
@@ -718,7 +1225,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0e] = Opcode{Name: "INC R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0e] = Opcode{Name: "INC R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R6)
 		if err != nil {
 			return err
@@ -729,7 +1236,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x0f] = Opcode{Name: "INC R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x0f] = Opcode{Name: "INC R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R7)
 		if err != nil {
 			return err
@@ -740,22 +1247,36 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x10] = Opcode{Name: "JBC bit,rel", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x10] = Opcode{Name: "JBC bit,rel", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		bit := operands[0]
+		set, err := vm.readLatchBit(bit)
+		if err != nil {
+			return err
+		}
+
+		if set {
+			if err := vm.WriteBit(bit, false); err != nil {
+				return err
+			}
+			vm.jumpRelative(operands[1])
+		}
 		return nil
 	}}
 
-	tbl[0x11] = Opcode{Name: "ACALL page0", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x11] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 0, operands[0])
 	}}
 
-	tbl[0x12] = Opcode{Name: "LCALL codeaddr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x12] = Opcode{Name: "LCALL codeaddr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		if err := vm.pushPC(); err != nil {
+			return err
+		}
+
+		vm.PC = uint16(operands[0])<<8 | uint16(operands[1])
 		return nil
 	}}
 
-	tbl[0x13] = Opcode{Name: "RRC A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x13] = Opcode{Name: "RRC A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -791,7 +1312,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x14] = Opcode{Name: "DEC A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x14] = Opcode{Name: "DEC A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		acc, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -802,9 +1323,9 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x15] = Opcode{Name: "DEC ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x15] = Opcode{Name: "DEC ramaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		addr := operands[0]
-		val, err := vm.ReadMem(addr)
+		val, err := vm.readLatch(addr)
 		if err != nil {
 			return err
 		}
@@ -814,7 +1335,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x16] = Opcode{Name: "DEC @R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x16] = Opcode{Name: "DEC @R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
@@ -825,7 +1346,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x17] = Opcode{Name: "DEC @R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x17] = Opcode{Name: "DEC @R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
@@ -836,7 +1357,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x18] = Opcode{Name: "DEC R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x18] = Opcode{Name: "DEC R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R0)
 		if err != nil {
 			return err
@@ -847,7 +1368,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x19] = Opcode{Name: "DEC R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x19] = Opcode{Name: "DEC R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R1)
 		if err != nil {
 			return err
@@ -858,7 +1379,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1a] = Opcode{Name: "DEC R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1a] = Opcode{Name: "DEC R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R2)
 		if err != nil {
 			return err
@@ -869,7 +1390,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1b] = Opcode{Name: "DEC R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1b] = Opcode{Name: "DEC R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R3)
 		if err != nil {
 			return err
@@ -880,7 +1401,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1c] = Opcode{Name: "DEC R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1c] = Opcode{Name: "DEC R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R4)
 		if err != nil {
 			return err
@@ -891,7 +1412,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1d] = Opcode{Name: "DEC R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1d] = Opcode{Name: "DEC R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R5)
 		if err != nil {
 			return err
@@ -902,7 +1423,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1e] = Opcode{Name: "DEC R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1e] = Opcode{Name: "DEC R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R6)
 		if err != nil {
 			return err
@@ -913,7 +1434,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x1f] = Opcode{Name: "DEC R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x1f] = Opcode{Name: "DEC R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R7)
 		if err != nil {
 			return err
@@ -924,22 +1445,27 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x20] = Opcode{Name: "JB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x20] = Opcode{Name: "JB bit,rel", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		set, err := vm.ReadBit(operands[0])
+		if err != nil {
+			return err
+		}
+
+		if set {
+			vm.jumpRelative(operands[1])
+		}
 		return nil
 	}}
 
-	tbl[0x21] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x21] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 1, operands[0])
 	}}
 
-	tbl[0x22] = Opcode{Name: "RET", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x22] = Opcode{Name: "RET", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return vm.popPC()
 	}}
 
-	tbl[0x23] = Opcode{Name: "RL A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x23] = Opcode{Name: "RL A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		acc, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -953,213 +1479,144 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	// TODO: flags
-	tbl[0x24] = Opcode{Name: "ADD A,#data", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
-		A += operands[0]
-		err = vm.WriteMem(SFR_ACC, A)
-		return err
+	tbl[0x24] = Opcode{Name: "ADD A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val := operands[0]
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x25] = Opcode{Name: "ADD A,dataaddr", Eval: func(vm *Machine, operands []byte) error {
-		addr := operands[0]
-
-		A, err := vm.ReadMem(SFR_ACC)
+	tbl[0x25] = Opcode{Name: "ADD A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadMem(operands[0])
 		if err != nil {
 			return err
 		}
 
-		val, err := vm.ReadMem(addr)
-		if err != nil {
-			return err
-		}
-
-		err = vm.WriteMem(addr, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x26] = Opcode{Name: "ADD A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
-		r0val, err := vm.DerefBank(LOC_R0)
+	tbl[0x26] = Opcode{Name: "ADD A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+r0val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x27] = Opcode{Name: "ADD A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x27] = Opcode{Name: "ADD A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x28] = Opcode{Name: "ADD A, R0", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x28] = Opcode{Name: "ADD A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R0)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x29] = Opcode{Name: "ADD A, R1", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x29] = Opcode{Name: "ADD A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R1)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2a] = Opcode{Name: "ADD A, R2", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x2a] = Opcode{Name: "ADD A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R2)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
-
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2b] = Opcode{Name: "ADD A, R3", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x2b] = Opcode{Name: "ADD A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R3)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2c] = Opcode{Name: "ADD A, R4", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x2c] = Opcode{Name: "ADD A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R4)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2d] = Opcode{Name: "ADD A, R5", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x2d] = Opcode{Name: "ADD A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R5)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2e] = Opcode{Name: "ADD A, R6", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
-		if err != nil {
-			return err
-		}
-
+	tbl[0x2e] = Opcode{Name: "ADD A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.ReadBankMem(LOC_R6)
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
+		return genericAdd(vm, val, false)
 	}}
 
-	// TODO: flags
-	tbl[0x2f] = Opcode{Name: "ADD A, R7", Eval: func(vm *Machine, operands []byte) error {
-		A, err := vm.ReadMem(SFR_ACC)
+	tbl[0x2f] = Opcode{Name: "ADD A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R7)
 		if err != nil {
 			return err
 		}
 
-		val, err := vm.ReadBankMem(LOC_R7)
+		return genericAdd(vm, val, false)
+	}}
+
+	tbl[0x30] = Opcode{Name: "JNB bit addr,code addr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		set, err := vm.ReadBit(operands[0])
 		if err != nil {
 			return err
 		}
 
-		err = vm.WriteMem(SFR_ACC, A+val)
-		return err
-	}}
-
-	tbl[0x30] = Opcode{Name: "JNB bit addr,code addr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+		if !set {
+			vm.jumpRelative(operands[1])
+		}
 		return nil
 	}}
 
-	tbl[0x31] = Opcode{Name: "ACALL code addr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x31] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 1, operands[0])
 	}}
 
-	tbl[0x32] = Opcode{Name: "RETI", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x32] = Opcode{Name: "RETI", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		if err := vm.popPC(); err != nil {
+			return err
+		}
+
+		// release the priority level of the interrupt being serviced so
+		// that pending interrupts of the same or lower priority can be taken
+		if vm.inService&INT_LEVEL_HIGH != 0 {
+			vm.inService &= ^INT_LEVEL_HIGH
+		} else {
+			vm.inService &= ^INT_LEVEL_LOW
+		}
+
+		vm.holdInterrupts = true
 		return nil
 	}}
 
-	tbl[0x33] = Opcode{Name: "RLC A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x33] = Opcode{Name: "RLC A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		acc, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -1188,417 +1645,511 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x34] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x34] = Opcode{Name: "ADDC A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val := operands[0]
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x35] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x35] = Opcode{Name: "ADDC A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadMem(operands[0])
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x36] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x36] = Opcode{Name: "ADDC A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x37] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x37] = Opcode{Name: "ADDC A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x38] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x38] = Opcode{Name: "ADDC A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x39] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x39] = Opcode{Name: "ADDC A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3a] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3a] = Opcode{Name: "ADDC A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R2)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3b] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3b] = Opcode{Name: "ADDC A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R3)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3c] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3c] = Opcode{Name: "ADDC A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R4)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3d] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3d] = Opcode{Name: "ADDC A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R5)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3e] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3e] = Opcode{Name: "ADDC A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R6)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x3f] = Opcode{Name: "ADDC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x3f] = Opcode{Name: "ADDC A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R7)
+		if err != nil {
+			return err
+		}
+
+		return genericAdd(vm, val, true)
 	}}
 
-	tbl[0x40] = Opcode{Name: "JC reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x40] = Opcode{Name: "JC reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		psw, err := vm.ReadMem(SFR_PSW)
+		if err != nil {
+			return err
+		}
+
+		if PSW_C(psw) {
+			vm.jumpRelative(operands[0])
+		}
 		return nil
 	}}
 
-	tbl[0x41] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x41] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 2, operands[0])
 	}}
 
-	tbl[0x42] = Opcode{Name: "ORL data addr,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x42] = Opcode{Name: "ORL data addr,A", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, operands[0], SFR_ACC)
 	}}
 
-	tbl[0x43] = Opcode{Name: "ORL data addr,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x43] = Opcode{Name: "ORL data addr,#data", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrlImm(vm, operands[0], operands[1])
 	}}
 
-	tbl[0x44] = Opcode{Name: "ORL A,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x44] = Opcode{Name: "ORL A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrlImm(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x45] = Opcode{Name: "ORL A,data addr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x45] = Opcode{Name: "ORL A,data addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x46] = Opcode{Name: "ORL A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		R0, err := vm.ReadBankMem(LOC_R0)
+	tbl[0x46] = Opcode{Name: "ORL A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
 		}
 
-		return genericOrl(vm, SFR_ACC, R0)
+		return genericOrlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x47] = Opcode{Name: "ORL A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		R1, err := vm.ReadBankMem(LOC_R1)
+	tbl[0x47] = Opcode{Name: "ORL A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
 		}
 
-		return genericOrl(vm, SFR_ACC, R1)
+		return genericOrlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x48] = Opcode{Name: "ORL A,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x48] = Opcode{Name: "ORL A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R0+vm.bankOffset())
 	}}
 
-	tbl[0x49] = Opcode{Name: "ORL A,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x49] = Opcode{Name: "ORL A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R1+vm.bankOffset())
 	}}
 
-	tbl[0x4a] = Opcode{Name: "ORL A,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4a] = Opcode{Name: "ORL A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R2+vm.bankOffset())
 	}}
 
-	tbl[0x4b] = Opcode{Name: "ORL A,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4b] = Opcode{Name: "ORL A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R3+vm.bankOffset())
 	}}
 
-	tbl[0x4c] = Opcode{Name: "ORL A,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4c] = Opcode{Name: "ORL A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R4+vm.bankOffset())
 	}}
 
-	tbl[0x4d] = Opcode{Name: "ORL A,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4d] = Opcode{Name: "ORL A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R5+vm.bankOffset())
 	}}
 
-	tbl[0x4e] = Opcode{Name: "ORL A,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4e] = Opcode{Name: "ORL A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R6+vm.bankOffset())
 	}}
 
-	tbl[0x4f] = Opcode{Name: "ORL A,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x4f] = Opcode{Name: "ORL A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericOrl(vm, SFR_ACC, LOC_R7+vm.bankOffset())
 	}}
 
-	tbl[0x50] = Opcode{Name: "JNC reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x50] = Opcode{Name: "JNC reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		psw, err := vm.ReadMem(SFR_PSW)
+		if err != nil {
+			return err
+		}
+
+		if !PSW_C(psw) {
+			vm.jumpRelative(operands[0])
+		}
 		return nil
 	}}
 
-	tbl[0x51] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x51] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 2, operands[0])
 	}}
 
-	tbl[0x52] = Opcode{Name: "ANL iram addr,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x52] = Opcode{Name: "ANL iram addr,A", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, operands[0], operands[1])
 	}}
 
-	tbl[0x53] = Opcode{Name: "ANL iram addr,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x53] = Opcode{Name: "ANL iram addr,#data", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnlImm(vm, operands[0], operands[1])
 	}}
 
-	tbl[0x54] = Opcode{Name: "ANL A,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x54] = Opcode{Name: "ANL A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnlImm(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x55] = Opcode{Name: "ANL A,iram addr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x55] = Opcode{Name: "ANL A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x56] = Opcode{Name: "ANL A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		r0, err := vm.ReadBankMem(LOC_R0)
+	tbl[0x56] = Opcode{Name: "ANL A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
 		}
 
-		return genericAnl(vm, SFR_ACC, r0)
+		return genericAnlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x57] = Opcode{Name: "ANL A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		r1, err := vm.ReadBankMem(LOC_R1)
+	tbl[0x57] = Opcode{Name: "ANL A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
 		}
 
-		return genericAnl(vm, SFR_ACC, r1)
+		return genericAnlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x58] = Opcode{Name: "ANL A,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x58] = Opcode{Name: "ANL A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R0+vm.bankOffset())
 	}}
 
-	tbl[0x59] = Opcode{Name: "ANL A,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x59] = Opcode{Name: "ANL A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R1+vm.bankOffset())
 	}}
 
-	tbl[0x5a] = Opcode{Name: "ANL A,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5a] = Opcode{Name: "ANL A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R2+vm.bankOffset())
 	}}
 
-	tbl[0x5b] = Opcode{Name: "ANL A,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5b] = Opcode{Name: "ANL A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R3+vm.bankOffset())
 	}}
 
-	tbl[0x5c] = Opcode{Name: "ANL A,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5c] = Opcode{Name: "ANL A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R4+vm.bankOffset())
 	}}
 
-	tbl[0x5d] = Opcode{Name: "ANL A,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5d] = Opcode{Name: "ANL A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R5+vm.bankOffset())
 	}}
 
-	tbl[0x5e] = Opcode{Name: "ANL A,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5e] = Opcode{Name: "ANL A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R6+vm.bankOffset())
 	}}
 
-	tbl[0x5f] = Opcode{Name: "ANL A,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x5f] = Opcode{Name: "ANL A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericAnl(vm, SFR_ACC, LOC_R7+vm.bankOffset())
 	}}
 
-	tbl[0x60] = Opcode{Name: "JZ reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x60] = Opcode{Name: "JZ reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
+
+		if A == 0 {
+			vm.jumpRelative(operands[0])
+		}
 		return nil
 	}}
 
-	tbl[0x61] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x61] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 3, operands[0])
 	}}
 
-	tbl[0x62] = Opcode{Name: "XRL iram addr,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x62] = Opcode{Name: "XRL iram addr,A", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, operands[0], SFR_ACC)
 	}}
 
-	tbl[0x63] = Opcode{Name: "XRL iram addr,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x63] = Opcode{Name: "XRL iram addr,#data", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrlImm(vm, operands[0], operands[1])
 	}}
 
-	tbl[0x64] = Opcode{Name: "XRL A,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x64] = Opcode{Name: "XRL A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrlImm(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x65] = Opcode{Name: "XRL A,iram addr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x65] = Opcode{Name: "XRL A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, operands[0])
 	}}
 
-	tbl[0x66] = Opcode{Name: "XRL A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		r0, err := vm.ReadBankMem(LOC_R0)
+	tbl[0x66] = Opcode{Name: "XRL A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
 		}
 
-		return genericXrl(vm, SFR_ACC, r0)
+		return genericXrlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x67] = Opcode{Name: "XRL A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		r1, err := vm.ReadBankMem(LOC_R1)
+	tbl[0x67] = Opcode{Name: "XRL A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
 		}
 
-		return genericXrl(vm, SFR_ACC, r1)
+		return genericXrlImm(vm, SFR_ACC, val)
 	}}
 
-	tbl[0x68] = Opcode{Name: "XRL A,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x68] = Opcode{Name: "XRL A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R0+vm.bankOffset())
 	}}
 
-	tbl[0x69] = Opcode{Name: "XRL A,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x69] = Opcode{Name: "XRL A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R1+vm.bankOffset())
 	}}
 
-	tbl[0x6a] = Opcode{Name: "XRL A,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6a] = Opcode{Name: "XRL A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R2+vm.bankOffset())
 	}}
 
-	tbl[0x6b] = Opcode{Name: "XRL A,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6b] = Opcode{Name: "XRL A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R3+vm.bankOffset())
 	}}
 
-	tbl[0x6c] = Opcode{Name: "XRL A,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6c] = Opcode{Name: "XRL A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R4+vm.bankOffset())
 	}}
 
-	tbl[0x6d] = Opcode{Name: "XRL A,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6d] = Opcode{Name: "XRL A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R5+vm.bankOffset())
 	}}
 
-	tbl[0x6e] = Opcode{Name: "XRL A,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6e] = Opcode{Name: "XRL A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R6+vm.bankOffset())
 	}}
 
-	tbl[0x6f] = Opcode{Name: "XRL A,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x6f] = Opcode{Name: "XRL A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXrl(vm, SFR_ACC, LOC_R7+vm.bankOffset())
 	}}
 
-	tbl[0x70] = Opcode{Name: "JNZ reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0x70] = Opcode{Name: "JNZ reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
 
-	tbl[0x71] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+		if A != 0 {
+			vm.jumpRelative(operands[0])
+		}
 		return nil
 	}}
 
-	tbl[0x72] = Opcode{Name: "ORL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x71] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 3, operands[0])
 	}}
 
-	tbl[0x73] = Opcode{Name: "JMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x72] = Opcode{Name: "ORL C,bit", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericOrlC(vm, operands[0], false)
 	}}
 
-	tbl[0x74] = Opcode{Name: "MOV A,#data", Eval: func(vm *Machine, operands []byte) error {
-		data := operands[0]
+	tbl[0x73] = Opcode{Name: "JMP @A+DPTR", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
 		}
-		err = vm.WriteMem(SFR_ACC, A+data)
+
+		dptr := vm.DPTR()
+
+		vm.PC = dptr + uint16(A)
+		return nil
+	}}
+
+	tbl[0x74] = Opcode{Name: "MOV A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		data := operands[0]
+		err := vm.WriteMem(SFR_ACC, data)
 		return err
 	}}
 
-	tbl[0x75] = Opcode{Name: "MOV ramaddr,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x75] = Opcode{Name: "MOV ramaddr,#data", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		data := operands[1]
 		err := vm.WriteMem(loc, data)
 		return err
 	}}
 
-	tbl[0x76] = Opcode{Name: "MOV @R0,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x76] = Opcode{Name: "MOV @R0,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.SetrefBank(LOC_R0, data)
 		return err
 	}}
 
-	tbl[0x77] = Opcode{Name: "MOV @R1,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x77] = Opcode{Name: "MOV @R1,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.SetrefBank(LOC_R1, data)
 		return err
 	}}
 
-	tbl[0x78] = Opcode{Name: "MOV R0,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x78] = Opcode{Name: "MOV R0,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R0, data)
 		return err
 	}}
 
-	tbl[0x79] = Opcode{Name: "MOV R1,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x79] = Opcode{Name: "MOV R1,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R1, data)
 		return err
 	}}
 
-	tbl[0x7a] = Opcode{Name: "MOV R2,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7a] = Opcode{Name: "MOV R2,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R2, data)
 		return err
 	}}
 
-	tbl[0x7b] = Opcode{Name: "MOV R3,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7b] = Opcode{Name: "MOV R3,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R3, data)
 		return err
 	}}
 
-	tbl[0x7c] = Opcode{Name: "MOV R4,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7c] = Opcode{Name: "MOV R4,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R4, data)
 		return err
 	}}
 
-	tbl[0x7d] = Opcode{Name: "MOV R5,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7d] = Opcode{Name: "MOV R5,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R5, data)
 		return err
 	}}
 
-	tbl[0x7e] = Opcode{Name: "MOV R6,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7e] = Opcode{Name: "MOV R6,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R6, data)
 		return err
 	}}
 
-	tbl[0x7f] = Opcode{Name: "MOV R7,#data", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x7f] = Opcode{Name: "MOV R7,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		data := operands[0]
 		err := vm.WriteBankMem(LOC_R7, data)
 		return err
 	}}
 
-	tbl[0x80] = Opcode{Name: "SJMP reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+	tbl[0x80] = Opcode{Name: "SJMP reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		vm.jumpRelative(operands[0])
 		return nil
 	}}
 
-	tbl[0x81] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x81] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 4, operands[0])
 	}}
 
-	tbl[0x82] = Opcode{Name: "ANL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x82] = Opcode{Name: "ANL C,bit", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAnlC(vm, operands[0], false)
 	}}
 
-	tbl[0x83] = Opcode{Name: "MOVC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x83] = Opcode{Name: "MOVC A,@A+PC", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		// PC already points at the next instruction
+		return genericMovc(vm, vm.PC)
 	}}
 
-	tbl[0x84] = Opcode{Name: "DIV", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x84] = Opcode{Name: "DIV AB", Size: 1, Cycles: 4, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
+
+		B, err := vm.ReadMem(SFR_B)
+		if err != nil {
+			return err
+		}
+
+		if err := vm.WriteBit(BIT_CY, false); err != nil {
+			return err
+		}
+
+		// A and B are undefined after a divide by zero, leave them as they are
+		if B == 0 {
+			return vm.WriteBit(BIT_OV, true)
+		}
+
+		if err := vm.WriteMem(SFR_ACC, A/B); err != nil {
+			return err
+		}
+
+		if err := vm.WriteMem(SFR_B, A%B); err != nil {
+			return err
+		}
+
+		return vm.WriteBit(BIT_OV, false)
 	}}
 
-	tbl[0x85] = Opcode{Name: "MOV addr1,addr2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x85] = Opcode{Name: "MOV addr1,addr2", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		// Yes, this is in reverse order for whatever reason
 		// https://www.win.tue.nl/~aeb/comp/8051/set8051.html#51mov
 		srcAddr := operands[0]
@@ -1613,7 +2164,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x86] = Opcode{Name: "MOV ramaddr,@R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x86] = Opcode{Name: "MOV ramaddr,@R0", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.DerefBank(LOC_R0)
@@ -1625,7 +2176,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x87] = Opcode{Name: "MOV ramaddr,@R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x87] = Opcode{Name: "MOV ramaddr,@R1", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.DerefBank(LOC_R1)
@@ -1637,7 +2188,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x88] = Opcode{Name: "MOV ramaddr,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x88] = Opcode{Name: "MOV ramaddr,R0", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R0)
@@ -1649,7 +2200,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x89] = Opcode{Name: "MOV ramaddr,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x89] = Opcode{Name: "MOV ramaddr,R1", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R1)
@@ -1661,7 +2212,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8a] = Opcode{Name: "MOV ramaddr,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8a] = Opcode{Name: "MOV ramaddr,R2", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R2)
@@ -1673,7 +2224,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8b] = Opcode{Name: "MOV ramaddr,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8b] = Opcode{Name: "MOV ramaddr,R3", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R3)
@@ -1685,7 +2236,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8c] = Opcode{Name: "MOV ramaddr,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8c] = Opcode{Name: "MOV ramaddr,R4", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R4)
@@ -1697,7 +2248,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8d] = Opcode{Name: "MOV ramaddr,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8d] = Opcode{Name: "MOV ramaddr,R5", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R5)
@@ -1709,7 +2260,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8e] = Opcode{Name: "MOV ramaddr,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8e] = Opcode{Name: "MOV ramaddr,R6", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R6)
@@ -1721,7 +2272,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x8f] = Opcode{Name: "MOV ramaddr,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0x8f] = Opcode{Name: "MOV ramaddr,R7", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 
 		val, err := vm.ReadBankMem(LOC_R7)
@@ -1733,117 +2284,191 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0x90] = Opcode{Name: "MOV", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x90] = Opcode{Name: "MOV DPTR,#data16", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return vm.SetDPTR(uint16(operands[0])<<8 | uint16(operands[1]))
 	}}
 
-	tbl[0x91] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x91] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 4, operands[0])
 	}}
 
-	tbl[0x92] = Opcode{Name: "MOV", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x92] = Opcode{Name: "MOV bit,C", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		carry, err := vm.ReadBit(BIT_CY)
+		if err != nil {
+			return err
+		}
+
+		return vm.WriteBit(operands[0], carry)
 	}}
 
-	tbl[0x93] = Opcode{Name: "MOVC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x93] = Opcode{Name: "MOVC A,@A+DPTR", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		dptr := vm.DPTR()
+
+		return genericMovc(vm, dptr)
 	}}
 
-	tbl[0x94] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x94] = Opcode{Name: "SUBB A,#data", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val := operands[0]
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x95] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x95] = Opcode{Name: "SUBB A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadMem(operands[0])
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x96] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x96] = Opcode{Name: "SUBB A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x97] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x97] = Opcode{Name: "SUBB A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x98] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x98] = Opcode{Name: "SUBB A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x99] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x99] = Opcode{Name: "SUBB A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x9a] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x9a] = Opcode{Name: "SUBB A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R2)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
+	}}
+
+	tbl[0x9b] = Opcode{Name: "SUBB A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R3)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
+	}}
+
+	tbl[0x9c] = Opcode{Name: "SUBB A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R4)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
+	}}
+
+	tbl[0x9d] = Opcode{Name: "SUBB A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R5)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
+	}}
+
+	tbl[0x9e] = Opcode{Name: "SUBB A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R6)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x9b] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0x9f] = Opcode{Name: "SUBB A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R7)
+		if err != nil {
+			return err
+		}
+
+		return genericSubb(vm, val)
 	}}
 
-	tbl[0x9c] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xa0] = Opcode{Name: "ORL C,/bit", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericOrlC(vm, operands[0], true)
 	}}
 
-	tbl[0x9d] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xa1] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 5, operands[0])
 	}}
 
-	tbl[0x9e] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xa2] = Opcode{Name: "MOV C,bit", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBit(operands[0])
+		if err != nil {
+			return err
+		}
 
-	tbl[0x9f] = Opcode{Name: "SUBB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		return vm.WriteBit(BIT_CY, val)
 	}}
 
-	tbl[0xa0] = Opcode{Name: "ORL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xa3] = Opcode{Name: "INC DPTR", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		dptr := vm.DPTR()
 
-	tbl[0xa1] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		return vm.SetDPTR(dptr + 1)
 	}}
 
-	tbl[0xa2] = Opcode{Name: "MOV", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xa4] = Opcode{Name: "MUL AB", Size: 1, Cycles: 4, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
 
-	tbl[0xa3] = Opcode{Name: "INC", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+		B, err := vm.ReadMem(SFR_B)
+		if err != nil {
+			return err
+		}
 
-	tbl[0xa4] = Opcode{Name: "MUL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		product := uint16(A) * uint16(B)
+
+		// B holds the high byte, A the low byte
+		if err := vm.WriteMem(SFR_B, byte(product>>8)); err != nil {
+			return err
+		}
+
+		if err := vm.WriteMem(SFR_ACC, byte(product)); err != nil {
+			return err
+		}
+
+		if err := vm.WriteBit(BIT_CY, false); err != nil {
+			return err
+		}
+
+		return vm.WriteBit(BIT_OV, product > 0xFF)
 	}}
 
-	tbl[0xa5] = Opcode{Name: "?", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xa5] = Opcode{Name: "?", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		// TODO: implement
 		return nil
 	}}
 
-	tbl[0xa6] = Opcode{Name: "MOV @R0,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xa6] = Opcode{Name: "MOV @R0,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1853,7 +2478,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xa7] = Opcode{Name: "MOV @R1,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xa7] = Opcode{Name: "MOV @R1,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1863,7 +2488,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xa8] = Opcode{Name: "MOV R0,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xa8] = Opcode{Name: "MOV R0,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1873,7 +2498,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xa9] = Opcode{Name: "MOV R1,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xa9] = Opcode{Name: "MOV R1,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1883,7 +2508,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xaa] = Opcode{Name: "MOV R2,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xaa] = Opcode{Name: "MOV R2,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1893,7 +2518,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xab] = Opcode{Name: "MOV R3,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xab] = Opcode{Name: "MOV R3,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1903,7 +2528,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xac] = Opcode{Name: "MOV R4,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xac] = Opcode{Name: "MOV R4,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1913,7 +2538,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xad] = Opcode{Name: "MOV R5,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xad] = Opcode{Name: "MOV R5,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1923,7 +2548,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xae] = Opcode{Name: "MOV R6,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xae] = Opcode{Name: "MOV R6,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1933,7 +2558,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xaf] = Opcode{Name: "MOV R7,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xaf] = Opcode{Name: "MOV R7,ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -1943,120 +2568,159 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xb0] = Opcode{Name: "ANL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb0] = Opcode{Name: "ANL C,/bit", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAnlC(vm, operands[0], true)
 	}}
 
-	tbl[0xb1] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb1] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 5, operands[0])
 	}}
 
-	tbl[0xb2] = Opcode{Name: "CPL bitaddr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb2] = Opcode{Name: "CPL bitaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericCplBit(vm, operands[0])
 	}}
 
-	tbl[0xb3] = Opcode{Name: "CPL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb3] = Opcode{Name: "CPL C", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericCplBit(vm, BIT_CY)
 	}}
 
-	tbl[0xb4] = Opcode{Name: "CJNE A,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xb4] = Opcode{Name: "CJNE A,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
 
-	tbl[0xb5] = Opcode{Name: "CJNE A,iram addr,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		return genericCjne(vm, A, operands[0], operands[1])
 	}}
 
-	tbl[0xb6] = Opcode{Name: "CJNE @R0,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xb5] = Opcode{Name: "CJNE A,iram addr,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
 
-	tbl[0xb7] = Opcode{Name: "CJNE @R1,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		val, err := vm.ReadMem(operands[0])
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, A, val, operands[1])
 	}}
 
-	tbl[0xb8] = Opcode{Name: "CJNE R0,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb6] = Opcode{Name: "CJNE @R0,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xb9] = Opcode{Name: "CJNE R1,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb7] = Opcode{Name: "CJNE @R1,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.DerefBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xba] = Opcode{Name: "CJNE R2,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb8] = Opcode{Name: "CJNE R0,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xbb] = Opcode{Name: "CJNE R3,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xb9] = Opcode{Name: "CJNE R1,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xbc] = Opcode{Name: "CJNE R4,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xba] = Opcode{Name: "CJNE R2,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R2)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xbd] = Opcode{Name: "CJNE R5,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xbb] = Opcode{Name: "CJNE R3,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R3)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xbe] = Opcode{Name: "CJNE R6,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xbc] = Opcode{Name: "CJNE R4,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R4)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xbf] = Opcode{Name: "CJNE R7,#data,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xbd] = Opcode{Name: "CJNE R5,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R5)
+		if err != nil {
+			return err
+		}
+
+		return genericCjne(vm, val, operands[0], operands[1])
 	}}
 
-	tbl[0xc0] = Opcode{Name: "PUSH ramaddr", Eval: func(vm *Machine, operands []byte) error {
-		addr := operands[0]
+	tbl[0xbe] = Opcode{Name: "CJNE R6,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R6)
+		if err != nil {
+			return err
+		}
 
-		val, err := vm.ReadMem(addr)
+		return genericCjne(vm, val, operands[0], operands[1])
+	}}
+
+	tbl[0xbf] = Opcode{Name: "CJNE R7,#data,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		val, err := vm.ReadBankMem(LOC_R7)
 		if err != nil {
 			return err
 		}
 
-		newSp := vm.SP + 1
+		return genericCjne(vm, val, operands[0], operands[1])
+	}}
+
+	tbl[0xc0] = Opcode{Name: "PUSH ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr := operands[0]
 
-		if err := vm.WriteMem(newSp, val); err != nil {
+		val, err := vm.ReadMem(addr)
+		if err != nil {
 			return err
 		}
 
-		vm.SP = newSp
-		return nil
+		return vm.stackPush(val)
 	}}
 
-	tbl[0xc1] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xc1] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 6, operands[0])
 	}}
 
-	tbl[0xc2] = Opcode{Name: "CLR", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xc2] = Opcode{Name: "CLR bitaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return vm.WriteBit(operands[0], false)
 	}}
 
-	tbl[0xc3] = Opcode{Name: "CLR", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xc3] = Opcode{Name: "CLR C", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return vm.WriteBit(BIT_CY, false)
 	}}
 
-	tbl[0xc4] = Opcode{Name: "SWAP A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xc4] = Opcode{Name: "SWAP A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2075,185 +2739,202 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xc5] = Opcode{Name: "XCH A,iram addr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xc5] = Opcode{Name: "XCH A,iram addr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		srcAddr := operands[0]
 		return genericXch(vm, SFR_ACC, srcAddr)
 	}}
 
-	tbl[0xc6] = Opcode{Name: "XCH A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		r0, err := vm.ReadBankMem(LOC_R0)
-		if err != nil {
-			return err
-		}
-
-		return genericXch(vm, SFR_ACC, r0)
+	tbl[0xc6] = Opcode{Name: "XCH A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericXchBank(vm, LOC_R0)
 	}}
 
-	tbl[0xc7] = Opcode{Name: "XCH A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		r1, err := vm.ReadBankMem(LOC_R1)
-		if err != nil {
-			return err
-		}
-
-		return genericXch(vm, SFR_ACC, r1)
+	tbl[0xc7] = Opcode{Name: "XCH A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericXchBank(vm, LOC_R1)
 	}}
 
-	tbl[0xc8] = Opcode{Name: "XCH A,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xc8] = Opcode{Name: "XCH A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R0+vm.bankOffset())
 	}}
 
-	tbl[0xc9] = Opcode{Name: "XCH A,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xc9] = Opcode{Name: "XCH A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R1+vm.bankOffset())
 	}}
 
-	tbl[0xca] = Opcode{Name: "XCH A,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xca] = Opcode{Name: "XCH A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R2+vm.bankOffset())
 	}}
 
-	tbl[0xcb] = Opcode{Name: "XCH A,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xcb] = Opcode{Name: "XCH A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R3+vm.bankOffset())
 	}}
 
-	tbl[0xcc] = Opcode{Name: "XCH A,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xcc] = Opcode{Name: "XCH A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R4+vm.bankOffset())
 	}}
 
-	tbl[0xcd] = Opcode{Name: "XCH A,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xcd] = Opcode{Name: "XCH A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R5+vm.bankOffset())
 	}}
 
-	tbl[0xce] = Opcode{Name: "XCH A,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xce] = Opcode{Name: "XCH A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R6+vm.bankOffset())
 	}}
 
-	tbl[0xcf] = Opcode{Name: "XCH A,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xcf] = Opcode{Name: "XCH A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		return genericXch(vm, SFR_ACC, LOC_R7+vm.bankOffset())
 	}}
 
-	tbl[0xd0] = Opcode{Name: "POP ramaddr", Eval: func(vm *Machine, operands []byte) error {
-		srcAddr := vm.SP
+	tbl[0xd0] = Opcode{Name: "POP ramaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
 		destAddr := operands[0]
 
-		val, err := vm.ReadMem(srcAddr)
+		val, err := vm.stackPop()
 		if err != nil {
 			return err
 		}
 
-		if err := vm.WriteMem(destAddr, val); err != nil {
-			return err
-		}
-
-		vm.SP -= 1
-		return nil
-	}}
-
-	tbl[0xd1] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+		return vm.WriteMem(destAddr, val)
 	}}
 
-	tbl[0xd2] = Opcode{Name: "SETB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd1] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 6, operands[0])
 	}}
 
-	tbl[0xd3] = Opcode{Name: "SETB", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd2] = Opcode{Name: "SETB bitaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return vm.WriteBit(operands[0], true)
 	}}
 
-	tbl[0xd4] = Opcode{Name: "DA", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd3] = Opcode{Name: "SETB C", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return vm.WriteBit(BIT_CY, true)
 	}}
 
-	tbl[0xd5] = Opcode{Name: "DJNZ", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
-	}}
+	tbl[0xd4] = Opcode{Name: "DA A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		A, err := vm.ReadMem(SFR_ACC)
+		if err != nil {
+			return err
+		}
 
-	tbl[0xd6] = Opcode{Name: "XCHD A,@R0", Eval: func(vm *Machine, operands []byte) error {
-		ptr, err := vm.ReadBankMem(LOC_R0)
+		psw, err := vm.ReadMem(SFR_PSW)
 		if err != nil {
 			return err
 		}
-		return genericXchd(vm, SFR_ACC, ptr)
+
+		// the carry can only be set here, never cleared
+		carry := PSW_C(psw)
+		val := uint16(A)
+
+		if val&0x0F > 9 || PSW_AC(psw) {
+			val += 0x06
+			if val > 0xFF {
+				carry = true
+			}
+		}
+
+		if (val>>4)&0x0F > 9 || carry {
+			val += 0x60
+			if val > 0xFF {
+				carry = true
+			}
+		}
+
+		if err := vm.WriteMem(SFR_ACC, byte(val)); err != nil {
+			return err
+		}
+
+		return vm.WriteBit(BIT_CY, carry)
 	}}
 
-	tbl[0xd7] = Opcode{Name: "XCHD A,@R1", Eval: func(vm *Machine, operands []byte) error {
-		ptr, err := vm.ReadBankMem(LOC_R1)
+	tbl[0xd5] = Opcode{Name: "DJNZ iram addr,reladdr", Size: 3, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr := operands[0]
+		val, err := vm.readLatch(addr)
 		if err != nil {
 			return err
 		}
-		return genericXchd(vm, SFR_ACC, ptr)
-	}}
 
-	tbl[0xd8] = Opcode{Name: "DJNZ R0,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
+		val -= 1
+		if err := vm.WriteMem(addr, val); err != nil {
+			return err
+		}
+
+		if val != 0 {
+			vm.jumpRelative(operands[1])
+		}
 		return nil
 	}}
 
-	tbl[0xd9] = Opcode{Name: "DJNZ R1,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd6] = Opcode{Name: "XCHD A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericXchdBank(vm, LOC_R0)
 	}}
 
-	tbl[0xda] = Opcode{Name: "DJNZ R2,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd7] = Opcode{Name: "XCHD A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
+		return genericXchdBank(vm, LOC_R1)
 	}}
 
-	tbl[0xdb] = Opcode{Name: "DJNZ R3,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd8] = Opcode{Name: "DJNZ R0,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R0, operands[0])
 	}}
 
-	tbl[0xdc] = Opcode{Name: "DJNZ R4,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xd9] = Opcode{Name: "DJNZ R1,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R1, operands[0])
 	}}
 
-	tbl[0xdd] = Opcode{Name: "DJNZ R5,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xda] = Opcode{Name: "DJNZ R2,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R2, operands[0])
 	}}
 
-	tbl[0xde] = Opcode{Name: "DJNZ R6,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xdb] = Opcode{Name: "DJNZ R3,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R3, operands[0])
 	}}
 
-	tbl[0xdf] = Opcode{Name: "DJNZ R7,reladdr", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xdc] = Opcode{Name: "DJNZ R4,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R4, operands[0])
 	}}
 
-	tbl[0xe0] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xdd] = Opcode{Name: "DJNZ R5,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R5, operands[0])
 	}}
 
-	tbl[0xe1] = Opcode{Name: "AJMP", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xde] = Opcode{Name: "DJNZ R6,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R6, operands[0])
 	}}
 
-	tbl[0xe2] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xdf] = Opcode{Name: "DJNZ R7,reladdr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericDjnzBank(vm, LOC_R7, operands[0])
 	}}
 
-	tbl[0xe3] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xe0] = Opcode{Name: "MOVX A,@DPTR", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr := vm.DPTR()
+
+		return genericMovxRead(vm, addr)
+	}}
+
+	tbl[0xe1] = Opcode{Name: "AJMP codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAjmp(vm, 7, operands[0])
+	}}
+
+	tbl[0xe2] = Opcode{Name: "MOVX A,@R0", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr, err := vm.xaddrBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericMovxRead(vm, addr)
+	}}
+
+	tbl[0xe3] = Opcode{Name: "MOVX A,@R1", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr, err := vm.xaddrBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericMovxRead(vm, addr)
 	}}
 
-	tbl[0xe4] = Opcode{Name: "CLR A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe4] = Opcode{Name: "CLR A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		err := vm.WriteMem(SFR_ACC, 0x00)
 		return err
 	}}
 
-	tbl[0xe5] = Opcode{Name: "MOV A,ramaddr", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe5] = Opcode{Name: "MOV A,ramaddr", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		loc := operands[0]
 		val, err := vm.ReadMem(loc)
 		if err != nil {
@@ -2264,7 +2945,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xe6] = Opcode{Name: "MOV A,@R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe6] = Opcode{Name: "MOV A,@R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.DerefBank(LOC_R0)
 		if err != nil {
 			return err
@@ -2274,7 +2955,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xe7] = Opcode{Name: "MOV A,@R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe7] = Opcode{Name: "MOV A,@R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		val, err := vm.DerefBank(LOC_R1)
 		if err != nil {
 			return err
@@ -2284,7 +2965,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xe8] = Opcode{Name: "MOV A,R0", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe8] = Opcode{Name: "MOV A,R0", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r0, err := vm.ReadBankMem(LOC_R0)
 		if err != nil {
 			return err
@@ -2293,7 +2974,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xe9] = Opcode{Name: "MOV A,R1", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xe9] = Opcode{Name: "MOV A,R1", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r1, err := vm.ReadBankMem(LOC_R1)
 		if err != nil {
 			return err
@@ -2302,7 +2983,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xea] = Opcode{Name: "MOV A,R2", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xea] = Opcode{Name: "MOV A,R2", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r2, err := vm.ReadBankMem(LOC_R2)
 		if err != nil {
 			return err
@@ -2311,7 +2992,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xeb] = Opcode{Name: "MOV A,R3", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xeb] = Opcode{Name: "MOV A,R3", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r3, err := vm.ReadBankMem(LOC_R3)
 		if err != nil {
 			return err
@@ -2320,7 +3001,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xec] = Opcode{Name: "MOV A,R4", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xec] = Opcode{Name: "MOV A,R4", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r4, err := vm.ReadBankMem(LOC_R4)
 		if err != nil {
 			return err
@@ -2329,7 +3010,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xed] = Opcode{Name: "MOV A,R5", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xed] = Opcode{Name: "MOV A,R5", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r5, err := vm.ReadBankMem(LOC_R5)
 		if err != nil {
 			return err
@@ -2338,7 +3019,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xee] = Opcode{Name: "MOV A,R6", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xee] = Opcode{Name: "MOV A,R6", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r6, err := vm.ReadBankMem(LOC_R6)
 		if err != nil {
 			return err
@@ -2347,7 +3028,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xef] = Opcode{Name: "MOV A,R7", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xef] = Opcode{Name: "MOV A,R7", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		r7, err := vm.ReadBankMem(LOC_R7)
 		if err != nil {
 			return err
@@ -2356,32 +3037,40 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xf0] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xf0] = Opcode{Name: "MOVX @DPTR,A", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr := vm.DPTR()
+
+		return genericMovxWrite(vm, addr)
 	}}
 
-	tbl[0xf1] = Opcode{Name: "ACALL", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xf1] = Opcode{Name: "ACALL codeaddr", Size: 2, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		return genericAcall(vm, 7, operands[0])
 	}}
 
-	tbl[0xf2] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xf2] = Opcode{Name: "MOVX @R0,A", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr, err := vm.xaddrBank(LOC_R0)
+		if err != nil {
+			return err
+		}
+
+		return genericMovxWrite(vm, addr)
 	}}
 
-	tbl[0xf3] = Opcode{Name: "MOVX", Eval: func(vm *Machine, operands []byte) error {
-		// TODO: implement
-		return nil
+	tbl[0xf3] = Opcode{Name: "MOVX @R1,A", Size: 1, Cycles: 2, Eval: func(vm *Machine, operands []byte) error {
+		addr, err := vm.xaddrBank(LOC_R1)
+		if err != nil {
+			return err
+		}
+
+		return genericMovxWrite(vm, addr)
 	}}
 
-	tbl[0xf4] = Opcode{Name: "CPL", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf4] = Opcode{Name: "CPL", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		// TODO: implement
 		return nil
 	}}
 
-	tbl[0xf5] = Opcode{Name: "MOV ramaddr,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf5] = Opcode{Name: "MOV ramaddr,A", Size: 2, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		dest := operands[0]
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
@@ -2392,7 +3081,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xf6] = Opcode{Name: "MOV @R0,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf6] = Opcode{Name: "MOV @R0,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2402,7 +3091,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xf7] = Opcode{Name: "MOV @R1,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf7] = Opcode{Name: "MOV @R1,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2412,7 +3101,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xf8] = Opcode{Name: "MOV R0,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf8] = Opcode{Name: "MOV R0,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2422,7 +3111,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xf9] = Opcode{Name: "MOV R1,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xf9] = Opcode{Name: "MOV R1,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2432,7 +3121,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xfa] = Opcode{Name: "MOV R2,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xfa] = Opcode{Name: "MOV R2,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2442,7 +3131,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xfb] = Opcode{Name: "MOV R3,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xfb] = Opcode{Name: "MOV R3,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2452,7 +3141,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xfc] = Opcode{Name: "MOV R4,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xfc] = Opcode{Name: "MOV R4,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2462,7 +3151,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xfd] = Opcode{Name: "MOV R5,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xfd] = Opcode{Name: "MOV R5,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2472,7 +3161,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xfe] = Opcode{Name: "MOV R6,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xfe] = Opcode{Name: "MOV R6,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -2482,7 +3171,7 @@ func operationTable() map[byte]Opcode {
 		return err
 	}}
 
-	tbl[0xff] = Opcode{Name: "MOV R7,A", Eval: func(vm *Machine, operands []byte) error {
+	tbl[0xff] = Opcode{Name: "MOV R7,A", Size: 1, Cycles: 1, Eval: func(vm *Machine, operands []byte) error {
 		A, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			return err
@@ -577,7 +577,8 @@ func TestOp0x25(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		actualValue, err := vm.ReadMem(tc.AddAmount)
+		// the sum goes into A, the source operand is left alone
+		actualValue, err := vm.ReadMem(SFR_ACC)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -755,13 +756,17 @@ func TestOp0x33(t *testing.T) {
 
 func TestOp0x74(t *testing.T) {
 	cases := []struct {
+		InitialValue  byte
 		ExpectedValue byte
 	}{
-		{ExpectedValue: 0xAA},
+		{InitialValue: 0x00, ExpectedValue: 0xAA},
+		{InitialValue: 0x11, ExpectedValue: 0x22}, // overwrites A, does not add to it
 	}
 
 	for _, tc := range cases {
 		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, tc.InitialValue)
+
 		if err := vm.Feed([]byte{0x74, tc.ExpectedValue}); err != nil {
 			t.Fatal(err)
 		}
@@ -1169,7 +1174,8 @@ func TestOp0xE6_0xE7(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := vm.WriteMem(tc.Ptr, tc.ExpectedValue); err != nil {
+		// pointers above 0x7F reach the upper IRAM, not the SFRs
+		if err := vm.WriteIndirect(tc.Ptr, tc.ExpectedValue); err != nil {
 			t.Fatal(err)
 		}
 
@@ -1724,14 +1730,37 @@ func TestOp0xC5(t *testing.T) {
 	t.Skipf("TODO: implement")
 }
 
+// Runs XCH/XCHD A,@Ri with A=3Ch and 5Bh at @Ri, pointing into
+// both the lower and the upper (indirect-only) RAM
+func testXchIndirect(t *testing.T, opcode byte, reg uint8, expectedA byte, expectedM byte) {
+	t.Helper()
+
+	for _, ptr := range []uint8{0x20, 0xF0} {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, 0x3C)
+		vm.WriteBankMem(reg, ptr)
+		vm.WriteIndirect(ptr, 0x5B)
+
+		if err := vm.Feed([]byte{opcode}); err != nil {
+			t.Fatal(err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		mem, _ := vm.ReadIndirect(ptr)
+		if A != expectedA || mem != expectedM {
+			t.Errorf("ptr %#02x: expected A=%#02x @Ri=%#02x, got A=%#02x @Ri=%#02x", ptr, expectedA, expectedM, A, mem)
+		}
+	}
+}
+
+// XCH A,@R0
 func TestOp0xC6(t *testing.T) {
-	// TODO: implement
-	t.Skipf("TODO: implement")
+	testXchIndirect(t, 0xC6, LOC_R0, 0x5B, 0x3C)
 }
 
+// XCH A,@R1
 func TestOp0xC7(t *testing.T) {
-	// TODO: implement
-	t.Skipf("TODO: implement")
+	testXchIndirect(t, 0xC7, LOC_R1, 0x5B, 0x3C)
 }
 
 func TestOp0xC8(t *testing.T) {
@@ -1774,14 +1803,14 @@ func TestOp0xCF(t *testing.T) {
 	t.Skipf("TODO: implement")
 }
 
+// XCHD A,@R0 only swaps the low nibbles
 func TestOp0xD6(t *testing.T) {
-	// TODO: implement
-	t.Skipf("TODO: implement")
+	testXchIndirect(t, 0xD6, LOC_R0, 0x3B, 0x5C)
 }
 
+// XCHD A,@R1
 func TestOp0xD7(t *testing.T) {
-	// TODO: implement
-	t.Skipf("TODO: implement")
+	testXchIndirect(t, 0xD7, LOC_R1, 0x3B, 0x5C)
 }
 
 func TestOp0xE4(t *testing.T) {
@@ -1816,14 +1845,14 @@ func TestOp0xC0_0xD0(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	currentSp := vm.SP
+	currentSp := vm.SP()
 	expectedNewSp := currentSp + 1
 
 	if err := vm.Feed([]byte{0xc0, srcAddr}); err != nil {
 		t.Fatal(err)
 	}
 
-	newSp := vm.SP
+	newSp := vm.SP()
 
 	if newSp != expectedNewSp {
 		t.Errorf("expected SP to be %d, got %d", expectedNewSp, newSp)
@@ -1853,3 +1882,1541 @@ func TestOp0xC0_0xD0(t *testing.T) {
 		t.Errorf("pop: expected POPped value to be %#02x, got %#02x", expectedValue, poppedValue)
 	}
 }
+
+func TestLoad(t *testing.T) {
+	vm := NewMachine()
+	vm.PC = 0x10
+
+	if err := vm.Load([]byte{0x74, 0xAA}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0 {
+		t.Errorf("expected PC to be reset to 0 after Load, got %#04x", vm.PC)
+	}
+
+	first, _ := vm.Code.Read(0)
+	second, _ := vm.Code.Read(1)
+	if first != 0x74 || second != 0xAA {
+		t.Errorf("expected program memory to start with 74 AA, got %02X %02X", first, second)
+	}
+
+	if vm.Code.Size() != 2 {
+		t.Errorf("expected loaded image to be 2B, got %dB", vm.Code.Size())
+	}
+
+	if err := vm.Load(make([]byte, vm.Code.Capacity()+1)); err == nil {
+		t.Errorf("expected Load to return error for oversized image, nil given")
+	}
+}
+
+func TestStep(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Program    []byte
+		ExpectedPC uint16
+		Addr       uint8
+		Expected   byte
+	}{
+		{Name: "INC A", Program: []byte{0x04}, ExpectedPC: 1, Addr: SFR_ACC, Expected: 0x01},
+		{Name: "MOV A,#data", Program: []byte{0x74, 0x5A}, ExpectedPC: 2, Addr: SFR_ACC, Expected: 0x5A},
+		{Name: "MOV ramaddr,#data", Program: []byte{0x75, 0x30, 0xAA}, ExpectedPC: 3, Addr: 0x30, Expected: 0xAA},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		if err := vm.Load(tc.Program); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := vm.Step(); err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("%s: expected PC to be %#04x, got %#04x", tc.Name, tc.ExpectedPC, vm.PC)
+		}
+
+		actual, err := vm.ReadMem(tc.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != tc.Expected {
+			t.Errorf("%s: expected value at %#02x to be %#02x, got %#02x", tc.Name, tc.Addr, tc.Expected, actual)
+		}
+	}
+}
+
+func TestStepTruncatedInstruction(t *testing.T) {
+	vm := NewMachine()
+	// MOV ramaddr,#data needs 2 more bytes
+	if err := vm.Load([]byte{0x00, 0x75, 0x30}); err != nil {
+		t.Fatal(err)
+	}
+	vm.PC = 1
+
+	if err := vm.Step(); err == nil {
+		t.Errorf("expected Step to return error for instruction running past program memory, nil given")
+	}
+}
+
+func TestStepTopOfCodeMemory(t *testing.T) {
+	cases := []struct {
+		Name       string
+		PC         uint16
+		Instr      []byte
+		ExpectedPC uint16
+	}{
+		{Name: "NOP at FFFFh wraps", PC: 0xFFFF, Instr: []byte{0x00}, ExpectedPC: 0x0000},
+		{Name: "INC A at FFFEh", PC: 0xFFFE, Instr: []byte{0x04}, ExpectedPC: 0xFFFF},
+		{Name: "MOV A,#data at FFFEh wraps", PC: 0xFFFE, Instr: []byte{0x74, 0x5A}, ExpectedPC: 0x0000},
+		{Name: "LJMP at FFFDh", PC: 0xFFFD, Instr: []byte{0x02, 0x12, 0x34}, ExpectedPC: 0x1234},
+	}
+
+	for _, tc := range cases {
+		program := make([]byte, CODE_MAX_SIZE)
+		copy(program[tc.PC:], tc.Instr)
+
+		vm := NewMachine()
+		if err := vm.Load(program); err != nil {
+			t.Fatal(err)
+		}
+		vm.PC = tc.PC
+
+		if err := vm.Step(); err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("%s: expected PC to be %#04x, got %#04x", tc.Name, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	program := []byte{
+		0x74, 0x05, // MOV A,#05h
+		0x04,       // INC A
+		0x04,       // INC A
+		0xF5, 0x30, // MOV 30h,A
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	executed, err := vm.Run(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 4 {
+		t.Errorf("expected 4 instructions to be executed, got %d", executed)
+	}
+
+	if vm.PC != uint16(len(program)) {
+		t.Errorf("expected PC to stop at end of image %#04x, got %#04x", len(program), vm.PC)
+	}
+
+	actual, _ := vm.ReadMem(0x30)
+	if actual != 0x07 {
+		t.Errorf("expected value at 0x30 to be 0x07, got %#02x", actual)
+	}
+}
+
+func TestRunLimit(t *testing.T) {
+	vm := NewMachine()
+	if err := vm.Load([]byte{0x04, 0x04, 0x04, 0x04}); err != nil {
+		t.Fatal(err)
+	}
+
+	executed, err := vm.Run(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 2 {
+		t.Errorf("expected 2 instructions to be executed, got %d", executed)
+	}
+
+	if vm.PC != 2 {
+		t.Errorf("expected PC to be 0x0002, got %#04x", vm.PC)
+	}
+}
+
+func TestOp0x01_0xE1(t *testing.T) {
+	cases := []struct {
+		Opcode     byte
+		PC         uint16
+		Addr       byte
+		ExpectedPC uint16
+	}{
+		{Opcode: 0x01, PC: 0x0000, Addr: 0x10, ExpectedPC: 0x0010},
+		{Opcode: 0x21, PC: 0x0000, Addr: 0x10, ExpectedPC: 0x0110},
+		{Opcode: 0x41, PC: 0x0000, Addr: 0xFF, ExpectedPC: 0x02FF},
+		{Opcode: 0x61, PC: 0x0000, Addr: 0x00, ExpectedPC: 0x0300},
+		{Opcode: 0x81, PC: 0x0800, Addr: 0x00, ExpectedPC: 0x0C00},
+		{Opcode: 0xA1, PC: 0x1234, Addr: 0x56, ExpectedPC: 0x1556},
+		{Opcode: 0xC1, PC: 0xF000, Addr: 0x01, ExpectedPC: 0xF601},
+		{Opcode: 0xE1, PC: 0x0000, Addr: 0xFF, ExpectedPC: 0x07FF},
+		// the upper 5 bits are taken from the address of the next instruction
+		{Opcode: 0x01, PC: 0x07FE, Addr: 0x00, ExpectedPC: 0x0800},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = tc.PC
+
+		if err := vm.Feed([]byte{tc.Opcode, tc.Addr}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("AJMP %#02x %#02x from %#04x: expected PC to be %#04x, got %#04x", tc.Opcode, tc.Addr, tc.PC, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestOp0x02(t *testing.T) {
+	vm := NewMachine()
+	vm.PC = 0x0100
+
+	if err := vm.Feed([]byte{0x02, 0xAB, 0xCD}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0xABCD {
+		t.Errorf("expected PC to be 0xabcd, got %#04x", vm.PC)
+	}
+}
+
+func TestOp0x73(t *testing.T) {
+	cases := []struct {
+		A          byte
+		DPH        byte
+		DPL        byte
+		ExpectedPC uint16
+	}{
+		{A: 0x00, DPH: 0x12, DPL: 0x34, ExpectedPC: 0x1234},
+		{A: 0x04, DPH: 0x12, DPL: 0x34, ExpectedPC: 0x1238},
+		{A: 0xFF, DPH: 0x00, DPL: 0x01, ExpectedPC: 0x0100},
+		{A: 0x02, DPH: 0xFF, DPL: 0xFF, ExpectedPC: 0x0001},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, tc.A)
+		vm.WriteMem(SFR_DPH, tc.DPH)
+		vm.WriteMem(SFR_DPL, tc.DPL)
+
+		if err := vm.Feed([]byte{0x73}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("expected PC to be %#04x, got %#04x", tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestOp0x80(t *testing.T) {
+	cases := []struct {
+		PC         uint16
+		Rel        byte
+		ExpectedPC uint16
+	}{
+		{PC: 0x0000, Rel: 0x00, ExpectedPC: 0x0002},
+		{PC: 0x0000, Rel: 0x10, ExpectedPC: 0x0012},
+		{PC: 0x0100, Rel: 0x7F, ExpectedPC: 0x0181},
+		{PC: 0x0100, Rel: 0x80, ExpectedPC: 0x0082},
+		{PC: 0x0100, Rel: 0xFE, ExpectedPC: 0x0100}, // SJMP $
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = tc.PC
+
+		if err := vm.Feed([]byte{0x80, tc.Rel}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("SJMP %#02x from %#04x: expected PC to be %#04x, got %#04x", tc.Rel, tc.PC, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestRunLoop(t *testing.T) {
+	program := []byte{
+		0x02, 0x00, 0x05, // 0000: LJMP 0005h
+		0x04,       // 0003: INC A (skipped)
+		0x04,       // 0004: INC A (skipped)
+		0x05, 0x30, // 0005: INC 30h
+		0x80, 0xFC, // 0007: SJMP 0005h
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	executed, err := vm.Run(21)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 21 {
+		t.Errorf("expected 21 instructions to be executed, got %d", executed)
+	}
+
+	counter, _ := vm.ReadMem(0x30)
+	if counter != 10 {
+		t.Errorf("expected loop counter at 0x30 to be 10, got %d", counter)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	if A != 0 {
+		t.Errorf("expected jumped-over INC A to be skipped, A is %#02x", A)
+	}
+}
+
+func TestRunHaltsOnJumpToSelf(t *testing.T) {
+	program := []byte{
+		0x04,       // 0000: INC A
+		0x80, 0xFE, // 0001: SJMP $
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	executed, err := vm.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 2 {
+		t.Errorf("expected Run to halt after 2 instructions, got %d", executed)
+	}
+
+	if vm.PC != 0x0001 {
+		t.Errorf("expected PC to stay at 0x0001, got %#04x", vm.PC)
+	}
+}
+
+func TestOp0x11_0xF1(t *testing.T) {
+	cases := []struct {
+		Opcode     byte
+		PC         uint16
+		Addr       byte
+		ExpectedPC uint16
+	}{
+		{Opcode: 0x11, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0020},
+		{Opcode: 0x31, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0120},
+		{Opcode: 0x51, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0220},
+		{Opcode: 0x71, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0320},
+		{Opcode: 0x91, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0420},
+		{Opcode: 0xB1, PC: 0x0000, Addr: 0x20, ExpectedPC: 0x0520},
+		{Opcode: 0xD1, PC: 0x1234, Addr: 0x20, ExpectedPC: 0x1620},
+		{Opcode: 0xF1, PC: 0x1234, Addr: 0x20, ExpectedPC: 0x1720},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = tc.PC
+
+		if err := vm.Feed([]byte{tc.Opcode, tc.Addr}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("ACALL %#02x %#02x from %#04x: expected PC to be %#04x, got %#04x", tc.Opcode, tc.Addr, tc.PC, tc.ExpectedPC, vm.PC)
+		}
+
+		sp, _ := vm.ReadMem(SFR_SP)
+		if sp != 0x09 {
+			t.Errorf("expected SP to be 0x09 after ACALL, got %#02x", sp)
+		}
+
+		returnAddr := tc.PC + 2
+		lo, _ := vm.ReadMem(0x08)
+		hi, _ := vm.ReadMem(0x09)
+		if lo != byte(returnAddr) || hi != byte(returnAddr>>8) {
+			t.Errorf("expected return address %#04x on stack as %#02x %#02x, got %#02x %#02x", returnAddr, byte(returnAddr), byte(returnAddr>>8), lo, hi)
+		}
+	}
+}
+
+func TestOp0x12_0x22(t *testing.T) {
+	vm := NewMachine()
+	vm.PC = 0x0123
+
+	if err := vm.Feed([]byte{0x12, 0x45, 0x67}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x4567 {
+		t.Errorf("expected PC to be 0x4567 after LCALL, got %#04x", vm.PC)
+	}
+
+	sp, _ := vm.ReadMem(SFR_SP)
+	if sp != 0x09 {
+		t.Errorf("expected SP to be 0x09 after LCALL, got %#02x", sp)
+	}
+
+	lo, _ := vm.ReadMem(0x08)
+	hi, _ := vm.ReadMem(0x09)
+	if lo != 0x26 || hi != 0x01 {
+		t.Errorf("expected return address to be pushed low byte first (0x26 0x01), got %#02x %#02x", lo, hi)
+	}
+
+	if err := vm.Feed([]byte{0x22}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x0126 {
+		t.Errorf("expected PC to be 0x0126 after RET, got %#04x", vm.PC)
+	}
+
+	sp, _ = vm.ReadMem(SFR_SP)
+	if sp != 0x07 {
+		t.Errorf("expected SP to be back at 0x07 after RET, got %#02x", sp)
+	}
+}
+
+func TestOp0x32(t *testing.T) {
+	cases := []struct {
+		InService byte
+		Expected  byte
+	}{
+		{InService: INT_LEVEL_LOW, Expected: 0},
+		{InService: INT_LEVEL_HIGH, Expected: 0},
+		{InService: INT_LEVEL_LOW | INT_LEVEL_HIGH, Expected: INT_LEVEL_LOW},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = 0x0100
+		vm.inService = tc.InService
+
+		if err := vm.Feed([]byte{0x12, 0x00, 0x0B}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := vm.Feed([]byte{0x32}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != 0x0103 {
+			t.Errorf("expected PC to be 0x0103 after RETI, got %#04x", vm.PC)
+		}
+
+		if vm.inService != tc.Expected {
+			t.Errorf("expected in-service levels %#02b to become %#02b after RETI, got %#02b", tc.InService, tc.Expected, vm.inService)
+		}
+	}
+}
+
+func TestNestedCalls(t *testing.T) {
+	program := []byte{
+		0x12, 0x00, 0x10, // 0000: LCALL 0010h
+		0x75, 0x32, 0x01, // 0003: MOV 32h,#01h
+		0x80, 0xFE, // 0006: SJMP $
+	}
+	program = append(program, make([]byte, 0x10-len(program))...)
+	program = append(program,
+		0x05, 0x30, // 0010: INC 30h
+		0x11, 0x20, // 0012: ACALL 0020h
+		0x05, 0x30, // 0014: INC 30h
+		0x22, // 0016: RET
+	)
+	program = append(program, make([]byte, 0x20-len(program))...)
+	program = append(program,
+		0x85, 0x81, 0x31, // 0020: MOV 31h,SP
+		0x05, 0x30, // 0023: INC 30h
+		0x22, // 0025: RET
+	)
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x0006 {
+		t.Errorf("expected to end up parked at 0x0006, got %#04x", vm.PC)
+	}
+
+	counter, _ := vm.ReadMem(0x30)
+	if counter != 3 {
+		t.Errorf("expected both subroutines to run (counter 3), got %d", counter)
+	}
+
+	deepestSp, _ := vm.ReadMem(0x31)
+	if deepestSp != 0x0B {
+		t.Errorf("expected SP to be 0x0b inside the nested call, got %#02x", deepestSp)
+	}
+
+	returned, _ := vm.ReadMem(0x32)
+	if returned != 0x01 {
+		t.Errorf("expected execution to continue after the outer call")
+	}
+
+	sp, _ := vm.ReadMem(SFR_SP)
+	if sp != 0x07 {
+		t.Errorf("expected SP to be back at 0x07, got %#02x", sp)
+	}
+}
+
+func TestOp0x40_0x50(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Opcode     byte
+		Carry      bool
+		ExpectedPC uint16
+	}{
+		{Name: "JC", Opcode: 0x40, Carry: true, ExpectedPC: 0x0112},
+		{Name: "JC", Opcode: 0x40, Carry: false, ExpectedPC: 0x0102},
+		{Name: "JNC", Opcode: 0x50, Carry: true, ExpectedPC: 0x0102},
+		{Name: "JNC", Opcode: 0x50, Carry: false, ExpectedPC: 0x0112},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = 0x0100
+
+		if tc.Carry {
+			vm.WriteMem(SFR_PSW, PSW_C_MASK)
+		}
+
+		if err := vm.Feed([]byte{tc.Opcode, 0x10}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("%s with carry %t: expected PC to be %#04x, got %#04x", tc.Name, tc.Carry, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestOp0x60_0x70(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Opcode     byte
+		A          byte
+		Rel        byte
+		ExpectedPC uint16
+	}{
+		{Name: "JZ", Opcode: 0x60, A: 0x00, Rel: 0xF0, ExpectedPC: 0x00F2},
+		{Name: "JZ", Opcode: 0x60, A: 0x01, Rel: 0xF0, ExpectedPC: 0x0102},
+		{Name: "JNZ", Opcode: 0x70, A: 0x00, Rel: 0xF0, ExpectedPC: 0x0102},
+		{Name: "JNZ", Opcode: 0x70, A: 0x80, Rel: 0xF0, ExpectedPC: 0x00F2},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.PC = 0x0100
+		vm.WriteMem(SFR_ACC, tc.A)
+
+		if err := vm.Feed([]byte{tc.Opcode, tc.Rel}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("%s with A=%#02x: expected PC to be %#04x, got %#04x", tc.Name, tc.A, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestOp0xB4_0xB5(t *testing.T) {
+	cases := []struct {
+		A             byte
+		Value         byte
+		ExpectedPC    uint16
+		ExpectedCarry bool
+	}{
+		{A: 0x10, Value: 0x10, ExpectedPC: 0x0103, ExpectedCarry: false},
+		{A: 0x10, Value: 0x20, ExpectedPC: 0x0113, ExpectedCarry: true},
+		{A: 0x20, Value: 0x10, ExpectedPC: 0x0113, ExpectedCarry: false},
+		{A: 0x7F, Value: 0x80, ExpectedPC: 0x0113, ExpectedCarry: true}, // unsigned comparison
+		{A: 0xFF, Value: 0x00, ExpectedPC: 0x0113, ExpectedCarry: false},
+	}
+
+	for _, tc := range cases {
+		for _, opcode := range []byte{0xB4, 0xB5} {
+			vm := NewMachine()
+			vm.PC = 0x0100
+			vm.WriteMem(SFR_ACC, tc.A)
+			// carry must be overwritten, not just set
+			vm.WriteMem(SFR_PSW, PSW_C_MASK)
+
+			operand := tc.Value
+			if opcode == 0xB5 {
+				vm.WriteMem(0x40, tc.Value)
+				operand = 0x40
+			}
+
+			if err := vm.Feed([]byte{opcode, operand, 0x10}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != tc.ExpectedPC {
+				t.Errorf("%#02x: CJNE A=%#02x,%#02x: expected PC to be %#04x, got %#04x", opcode, tc.A, tc.Value, tc.ExpectedPC, vm.PC)
+			}
+
+			psw, _ := vm.ReadMem(SFR_PSW)
+			if PSW_C(psw) != tc.ExpectedCarry {
+				t.Errorf("%#02x: CJNE A=%#02x,%#02x: expected carry to be %t", opcode, tc.A, tc.Value, tc.ExpectedCarry)
+			}
+		}
+	}
+}
+
+func TestOp0xB6_0xB7(t *testing.T) {
+	cases := []struct {
+		Opcode byte
+		Reg    uint8
+		Bank   byte
+	}{
+		{Opcode: 0xB6, Reg: LOC_R0, Bank: 0},
+		{Opcode: 0xB6, Reg: LOC_R0, Bank: 3},
+		{Opcode: 0xB7, Reg: LOC_R1, Bank: 0},
+		{Opcode: 0xB7, Reg: LOC_R1, Bank: 2},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.SetBankNo(tc.Bank)
+		vm.WriteBankMem(tc.Reg, 0x40)
+		vm.WriteMem(0x40, 0x05)
+
+		if err := vm.Feed([]byte{tc.Opcode, 0x06, 0x7F}); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != 0x0082 {
+			t.Errorf("%#02x bank %d: expected PC to be 0x0082, got %#04x", tc.Opcode, tc.Bank, vm.PC)
+		}
+
+		psw, _ := vm.ReadMem(SFR_PSW)
+		if !PSW_C(psw) {
+			t.Errorf("%#02x bank %d: expected carry to be set", tc.Opcode, tc.Bank)
+		}
+	}
+}
+
+func TestOp0xB8_0xBF(t *testing.T) {
+	for reg := uint8(LOC_R0); reg <= LOC_R7; reg++ {
+		for bank := byte(0); bank < 4; bank++ {
+			opcode := 0xB8 + reg
+
+			vm := NewMachine()
+			vm.SetBankNo(bank)
+			vm.WriteBankMem(reg, 0x33)
+
+			if err := vm.Feed([]byte{opcode, 0x33, 0x10}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != 0x0003 {
+				t.Errorf("CJNE R%d (bank %d) with equal values: expected fall through to 0x0003, got %#04x", reg, bank, vm.PC)
+			}
+
+			vm.PC = 0
+			if err := vm.Feed([]byte{opcode, 0x34, 0x10}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != 0x0013 {
+				t.Errorf("CJNE R%d (bank %d) with different values: expected jump to 0x0013, got %#04x", reg, bank, vm.PC)
+			}
+		}
+	}
+}
+
+func TestOp0xD5(t *testing.T) {
+	cases := []struct {
+		Initial    byte
+		Expected   byte
+		ExpectedPC uint16
+	}{
+		{Initial: 0x02, Expected: 0x01, ExpectedPC: 0x0000},
+		{Initial: 0x01, Expected: 0x00, ExpectedPC: 0x0003},
+		{Initial: 0x00, Expected: 0xFF, ExpectedPC: 0x0000},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(0x30, tc.Initial)
+
+		if err := vm.Feed([]byte{0xD5, 0x30, 0xFD}); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ := vm.ReadMem(0x30)
+		if actual != tc.Expected {
+			t.Errorf("expected 0x30 to be decremented to %#02x, got %#02x", tc.Expected, actual)
+		}
+
+		if vm.PC != tc.ExpectedPC {
+			t.Errorf("DJNZ 30h from %#02x: expected PC to be %#04x, got %#04x", tc.Initial, tc.ExpectedPC, vm.PC)
+		}
+	}
+}
+
+func TestOp0xD8_0xDF(t *testing.T) {
+	for reg := uint8(LOC_R0); reg <= LOC_R7; reg++ {
+		for bank := byte(0); bank < 4; bank++ {
+			opcode := 0xD8 + reg
+
+			vm := NewMachine()
+			vm.SetBankNo(bank)
+			vm.WriteBankMem(reg, 0x02)
+
+			if err := vm.Feed([]byte{opcode, 0xFE}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != 0x0000 {
+				t.Errorf("DJNZ R%d (bank %d): expected jump back to 0x0000, got %#04x", reg, bank, vm.PC)
+			}
+
+			if err := vm.Feed([]byte{opcode, 0xFE}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != 0x0002 {
+				t.Errorf("DJNZ R%d (bank %d): expected fall through to 0x0002, got %#04x", reg, bank, vm.PC)
+			}
+
+			actual, _ := vm.ReadMem(reg + bank*BANK_SIZE)
+			if actual != 0 {
+				t.Errorf("DJNZ R%d (bank %d): expected register to reach 0, got %#02x", reg, bank, actual)
+			}
+		}
+	}
+}
+
+func TestRunCountedLoop(t *testing.T) {
+	// same shape as the delay loop in examples/blink.bin
+	program := []byte{
+		0x7B, 0x02, // 0000: MOV R3,#2
+		0x7A, 0x03, // 0002: MOV R2,#3
+		0x79, 0x04, // 0004: MOV R1,#4
+		0x05, 0x30, // 0006: INC 30h
+		0xD9, 0xFC, // 0008: DJNZ R1,0006h
+		0xDA, 0xF8, // 000A: DJNZ R2,0004h
+		0xDB, 0xF4, // 000C: DJNZ R3,0002h
+		0xD9, 0xFE, // 000E: DJNZ R1,$
+		0x80, 0xFE, // 0010: SJMP $
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(10000); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x0010 {
+		t.Errorf("expected to end up parked at 0x0010, got %#04x", vm.PC)
+	}
+
+	counter, _ := vm.ReadMem(0x30)
+	if counter != 2*3*4 {
+		t.Errorf("expected inner loop body to run %d times, got %d", 2*3*4, counter)
+	}
+}
+
+func TestOp0x10_0x20_0x30(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Opcode      byte
+		Set         bool
+		ExpectedPC  uint16
+		ExpectedBit bool
+	}{
+		{Name: "JBC", Opcode: 0x10, Set: true, ExpectedPC: 0x0013, ExpectedBit: false},
+		{Name: "JBC", Opcode: 0x10, Set: false, ExpectedPC: 0x0003, ExpectedBit: false},
+		{Name: "JB", Opcode: 0x20, Set: true, ExpectedPC: 0x0013, ExpectedBit: true},
+		{Name: "JB", Opcode: 0x20, Set: false, ExpectedPC: 0x0003, ExpectedBit: false},
+		{Name: "JNB", Opcode: 0x30, Set: true, ExpectedPC: 0x0003, ExpectedBit: true},
+		{Name: "JNB", Opcode: 0x30, Set: false, ExpectedPC: 0x0013, ExpectedBit: false},
+	}
+
+	for _, tc := range cases {
+		for _, bit := range []uint8{0x05, 0xE7} {
+			vm := NewMachine()
+			vm.WriteBit(bit, tc.Set)
+
+			if err := vm.Feed([]byte{tc.Opcode, bit, 0x10}); err != nil {
+				t.Fatal(err)
+			}
+
+			if vm.PC != tc.ExpectedPC {
+				t.Errorf("%s bit %#02x set %t: expected PC to be %#04x, got %#04x", tc.Name, bit, tc.Set, tc.ExpectedPC, vm.PC)
+			}
+
+			actual, _ := vm.ReadBit(bit)
+			if actual != tc.ExpectedBit {
+				t.Errorf("%s bit %#02x set %t: expected bit afterwards to be %t", tc.Name, bit, tc.Set, tc.ExpectedBit)
+			}
+		}
+	}
+}
+
+func TestCarryLogic(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Opcode   byte
+		Carry    bool
+		Bit      bool
+		Expected bool
+	}{
+		{Name: "ORL C,bit", Opcode: 0x72, Carry: false, Bit: false, Expected: false},
+		{Name: "ORL C,bit", Opcode: 0x72, Carry: false, Bit: true, Expected: true},
+		{Name: "ORL C,bit", Opcode: 0x72, Carry: true, Bit: false, Expected: true},
+		{Name: "ORL C,/bit", Opcode: 0xA0, Carry: false, Bit: false, Expected: true},
+		{Name: "ORL C,/bit", Opcode: 0xA0, Carry: false, Bit: true, Expected: false},
+		{Name: "ORL C,/bit", Opcode: 0xA0, Carry: true, Bit: true, Expected: true},
+		{Name: "ANL C,bit", Opcode: 0x82, Carry: true, Bit: true, Expected: true},
+		{Name: "ANL C,bit", Opcode: 0x82, Carry: true, Bit: false, Expected: false},
+		{Name: "ANL C,bit", Opcode: 0x82, Carry: false, Bit: true, Expected: false},
+		{Name: "ANL C,/bit", Opcode: 0xB0, Carry: true, Bit: false, Expected: true},
+		{Name: "ANL C,/bit", Opcode: 0xB0, Carry: true, Bit: true, Expected: false},
+		{Name: "ANL C,/bit", Opcode: 0xB0, Carry: false, Bit: false, Expected: false},
+		{Name: "MOV C,bit", Opcode: 0xA2, Carry: false, Bit: true, Expected: true},
+		{Name: "MOV C,bit", Opcode: 0xA2, Carry: true, Bit: false, Expected: false},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteBit(BIT_CY, tc.Carry)
+		vm.WriteBit(0x42, tc.Bit)
+
+		if err := vm.Feed([]byte{tc.Opcode, 0x42}); err != nil {
+			t.Fatal(err)
+		}
+
+		carry, _ := vm.ReadBit(BIT_CY)
+		if carry != tc.Expected {
+			t.Errorf("%s with C=%t bit=%t: expected C to be %t", tc.Name, tc.Carry, tc.Bit, tc.Expected)
+		}
+	}
+}
+
+func TestOp0x92(t *testing.T) {
+	for _, carry := range []bool{true, false} {
+		vm := NewMachine()
+		vm.WriteBit(BIT_CY, carry)
+		vm.WriteBit(0x91, !carry)
+
+		if err := vm.Feed([]byte{0x92, 0x91}); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ := vm.ReadBit(0x91)
+		if actual != carry {
+			t.Errorf("expected P1.1 to be %t, got %t", carry, actual)
+		}
+	}
+}
+
+func TestBitSetClearComplement(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Opcode   byte
+		Bit      uint8
+		Initial  bool
+		Expected bool
+	}{
+		{Name: "SETB bit", Opcode: 0xD2, Bit: 0x10, Initial: false, Expected: true},
+		{Name: "SETB C", Opcode: 0xD3, Bit: BIT_CY, Initial: false, Expected: true},
+		{Name: "CLR bit", Opcode: 0xC2, Bit: 0x8C, Initial: true, Expected: false},
+		{Name: "CLR C", Opcode: 0xC3, Bit: BIT_CY, Initial: true, Expected: false},
+		{Name: "CPL bit", Opcode: 0xB2, Bit: 0xB5, Initial: true, Expected: false},
+		{Name: "CPL bit", Opcode: 0xB2, Bit: 0xB5, Initial: false, Expected: true},
+		{Name: "CPL C", Opcode: 0xB3, Bit: BIT_CY, Initial: false, Expected: true},
+		{Name: "CPL C", Opcode: 0xB3, Bit: BIT_CY, Initial: true, Expected: false},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteBit(tc.Bit, tc.Initial)
+
+		instruction := []byte{tc.Opcode}
+		if tc.Bit != BIT_CY {
+			instruction = append(instruction, tc.Bit)
+		}
+
+		if err := vm.Feed(instruction); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ := vm.ReadBit(tc.Bit)
+		if actual != tc.Expected {
+			t.Errorf("%s %#02x from %t: expected %t, got %t", tc.Name, tc.Bit, tc.Initial, tc.Expected, actual)
+		}
+	}
+}
+
+func TestAluAdd(t *testing.T) {
+	cases := []struct {
+		A     byte
+		B     byte
+		Carry bool
+		Value byte
+		CY    bool
+		AC    bool
+		OV    bool
+	}{
+		{A: 0x00, B: 0x00, Carry: false, Value: 0x00, CY: false, AC: false, OV: false},
+		{A: 0x00, B: 0x00, Carry: true, Value: 0x01, CY: false, AC: false, OV: false},
+		{A: 0x0F, B: 0x01, Carry: false, Value: 0x10, CY: false, AC: true, OV: false},
+		{A: 0x0F, B: 0x00, Carry: true, Value: 0x10, CY: false, AC: true, OV: false},
+		{A: 0x7F, B: 0x01, Carry: false, Value: 0x80, CY: false, AC: true, OV: true},
+		{A: 0x7F, B: 0x00, Carry: true, Value: 0x80, CY: false, AC: true, OV: true},
+		{A: 0x7F, B: 0x7F, Carry: false, Value: 0xFE, CY: false, AC: true, OV: true},
+		{A: 0x80, B: 0x80, Carry: false, Value: 0x00, CY: true, AC: false, OV: true},
+		{A: 0x80, B: 0x7F, Carry: false, Value: 0xFF, CY: false, AC: false, OV: false},
+		{A: 0x80, B: 0x7F, Carry: true, Value: 0x00, CY: true, AC: true, OV: false},
+		{A: 0xFF, B: 0x01, Carry: false, Value: 0x00, CY: true, AC: true, OV: false},
+		{A: 0xFF, B: 0xFF, Carry: false, Value: 0xFE, CY: true, AC: true, OV: false},
+		{A: 0xFF, B: 0xFF, Carry: true, Value: 0xFF, CY: true, AC: true, OV: false},
+		{A: 0xFF, B: 0x80, Carry: false, Value: 0x7F, CY: true, AC: false, OV: true},
+		{A: 0xC3, B: 0xAA, Carry: false, Value: 0x6D, CY: true, AC: false, OV: true}, // datasheet example
+	}
+
+	for _, tc := range cases {
+		res := aluAdd(tc.A, tc.B, tc.Carry)
+
+		if res.Value != tc.Value || res.CY != tc.CY || res.AC != tc.AC || res.OV != tc.OV {
+			t.Errorf("%#02x + %#02x + %t: expected %#02x CY=%t AC=%t OV=%t, got %#02x CY=%t AC=%t OV=%t",
+				tc.A, tc.B, tc.Carry, tc.Value, tc.CY, tc.AC, tc.OV, res.Value, res.CY, res.AC, res.OV)
+		}
+	}
+}
+
+func TestAluSubb(t *testing.T) {
+	cases := []struct {
+		A      byte
+		B      byte
+		Borrow bool
+		Value  byte
+		CY     bool
+		AC     bool
+		OV     bool
+	}{
+		{A: 0x00, B: 0x00, Borrow: false, Value: 0x00, CY: false, AC: false, OV: false},
+		{A: 0x00, B: 0x00, Borrow: true, Value: 0xFF, CY: true, AC: true, OV: false},
+		{A: 0x00, B: 0x01, Borrow: false, Value: 0xFF, CY: true, AC: true, OV: false},
+		{A: 0x10, B: 0x01, Borrow: false, Value: 0x0F, CY: false, AC: true, OV: false},
+		{A: 0x80, B: 0x01, Borrow: false, Value: 0x7F, CY: false, AC: true, OV: true},
+		{A: 0x80, B: 0x00, Borrow: true, Value: 0x7F, CY: false, AC: true, OV: true},
+		{A: 0x7F, B: 0xFF, Borrow: false, Value: 0x80, CY: true, AC: false, OV: true},
+		{A: 0x7F, B: 0x80, Borrow: false, Value: 0xFF, CY: true, AC: false, OV: true},
+		{A: 0x7F, B: 0x7F, Borrow: false, Value: 0x00, CY: false, AC: false, OV: false},
+		{A: 0x7F, B: 0x7F, Borrow: true, Value: 0xFF, CY: true, AC: true, OV: false},
+		{A: 0xFF, B: 0xFF, Borrow: false, Value: 0x00, CY: false, AC: false, OV: false},
+		{A: 0xFF, B: 0x7F, Borrow: false, Value: 0x80, CY: false, AC: false, OV: false},
+		{A: 0xFF, B: 0x80, Borrow: true, Value: 0x7E, CY: false, AC: false, OV: false},
+		{A: 0xC9, B: 0x54, Borrow: true, Value: 0x74, CY: false, AC: false, OV: true}, // datasheet example
+	}
+
+	for _, tc := range cases {
+		res := aluSubb(tc.A, tc.B, tc.Borrow)
+
+		if res.Value != tc.Value || res.CY != tc.CY || res.AC != tc.AC || res.OV != tc.OV {
+			t.Errorf("%#02x - %#02x - %t: expected %#02x CY=%t AC=%t OV=%t, got %#02x CY=%t AC=%t OV=%t",
+				tc.A, tc.B, tc.Borrow, tc.Value, tc.CY, tc.AC, tc.OV, res.Value, res.CY, res.AC, res.OV)
+		}
+	}
+}
+
+// Every ADD, ADDC and SUBB opcode should go through the same core:
+// A=0x7F, operand=0x01 and CY=1 exercises carry-in and all three flags
+func TestOp0x24_0x3F_0x94_0x9F(t *testing.T) {
+	cases := []struct {
+		Base     byte
+		Name     string
+		Expected aluResult
+	}{
+		{Base: 0x24, Name: "ADD", Expected: aluAdd(0x7F, 0x01, false)},
+		{Base: 0x34, Name: "ADDC", Expected: aluAdd(0x7F, 0x01, true)},
+		{Base: 0x94, Name: "SUBB", Expected: aluSubb(0x7F, 0x01, true)},
+	}
+
+	for _, tc := range cases {
+		for offset := byte(0); offset < 12; offset++ {
+			opcode := tc.Base + offset
+
+			vm := NewMachine()
+			vm.SetBankNo(2)
+			vm.WriteMem(SFR_ACC, 0x7F)
+			vm.WriteBit(BIT_CY, true)
+
+			var instruction []byte
+			switch {
+			case offset == 0: // #data
+				instruction = []byte{opcode, 0x01}
+			case offset == 1: // iram addr
+				vm.WriteMem(0x40, 0x01)
+				instruction = []byte{opcode, 0x40}
+			case offset < 4: // @R0, @R1
+				vm.WriteBankMem(offset-2, 0x40)
+				vm.WriteMem(0x40, 0x01)
+				instruction = []byte{opcode}
+			default: // R0-R7
+				vm.WriteBankMem(offset-4, 0x01)
+				instruction = []byte{opcode}
+			}
+
+			if err := vm.Feed(instruction); err != nil {
+				t.Fatal(err)
+			}
+
+			A, _ := vm.ReadMem(SFR_ACC)
+			psw, _ := vm.ReadMem(SFR_PSW)
+
+			if A != tc.Expected.Value {
+				t.Errorf("%s %#02x: expected A to be %#02x, got %#02x", tc.Name, opcode, tc.Expected.Value, A)
+			}
+
+			if PSW_C(psw) != tc.Expected.CY || PSW_AC(psw) != tc.Expected.AC || PSW_OV(psw) != tc.Expected.OV {
+				t.Errorf("%s %#02x: expected CY=%t AC=%t OV=%t, got PSW %#08b", tc.Name, opcode, tc.Expected.CY, tc.Expected.AC, tc.Expected.OV, psw)
+			}
+
+			if vm.bankNo() != 2 {
+				t.Errorf("%s %#02x: flag update clobbered the register bank selection", tc.Name, opcode)
+			}
+		}
+	}
+}
+
+func TestOp0xA4(t *testing.T) {
+	cases := []struct {
+		A          byte
+		B          byte
+		ExpectedA  byte
+		ExpectedB  byte
+		ExpectedOV bool
+	}{
+		{A: 0x00, B: 0xFF, ExpectedA: 0x00, ExpectedB: 0x00, ExpectedOV: false},
+		{A: 0x0F, B: 0x11, ExpectedA: 0xFF, ExpectedB: 0x00, ExpectedOV: false},
+		{A: 0x10, B: 0x10, ExpectedA: 0x00, ExpectedB: 0x01, ExpectedOV: true},
+		{A: 0x50, B: 0xA0, ExpectedA: 0x00, ExpectedB: 0x32, ExpectedOV: true}, // datasheet example
+		{A: 0xFF, B: 0xFF, ExpectedA: 0x01, ExpectedB: 0xFE, ExpectedOV: true},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, tc.A)
+		vm.WriteMem(SFR_B, tc.B)
+		vm.WriteBit(BIT_CY, true)
+
+		if err := vm.Feed([]byte{0xA4}); err != nil {
+			t.Fatal(err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		B, _ := vm.ReadMem(SFR_B)
+		psw, _ := vm.ReadMem(SFR_PSW)
+
+		if A != tc.ExpectedA || B != tc.ExpectedB {
+			t.Errorf("%#02x * %#02x: expected B:A to be %02X:%02X, got %02X:%02X", tc.A, tc.B, tc.ExpectedB, tc.ExpectedA, B, A)
+		}
+
+		if PSW_OV(psw) != tc.ExpectedOV {
+			t.Errorf("%#02x * %#02x: expected OV to be %t", tc.A, tc.B, tc.ExpectedOV)
+		}
+
+		if PSW_C(psw) {
+			t.Errorf("%#02x * %#02x: expected carry to be cleared", tc.A, tc.B)
+		}
+	}
+}
+
+func TestOp0x84(t *testing.T) {
+	cases := []struct {
+		A          byte
+		B          byte
+		ExpectedA  byte
+		ExpectedB  byte
+		ExpectedOV bool
+	}{
+		{A: 0xFB, B: 0x12, ExpectedA: 0x0D, ExpectedB: 0x11, ExpectedOV: false}, // datasheet example
+		{A: 0x00, B: 0x01, ExpectedA: 0x00, ExpectedB: 0x00, ExpectedOV: false},
+		{A: 0xFF, B: 0x01, ExpectedA: 0xFF, ExpectedB: 0x00, ExpectedOV: false},
+		{A: 0x01, B: 0xFF, ExpectedA: 0x00, ExpectedB: 0x01, ExpectedOV: false},
+		{A: 0x42, B: 0x00, ExpectedA: 0x42, ExpectedB: 0x00, ExpectedOV: true},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, tc.A)
+		vm.WriteMem(SFR_B, tc.B)
+		vm.WriteBit(BIT_CY, true)
+
+		if err := vm.Feed([]byte{0x84}); err != nil {
+			t.Fatal(err)
+		}
+
+		psw, _ := vm.ReadMem(SFR_PSW)
+		if PSW_OV(psw) != tc.ExpectedOV {
+			t.Errorf("%#02x / %#02x: expected OV to be %t", tc.A, tc.B, tc.ExpectedOV)
+		}
+
+		if PSW_C(psw) {
+			t.Errorf("%#02x / %#02x: expected carry to be cleared", tc.A, tc.B)
+		}
+
+		if tc.ExpectedOV {
+			continue // A and B are undefined
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		B, _ := vm.ReadMem(SFR_B)
+		if A != tc.ExpectedA || B != tc.ExpectedB {
+			t.Errorf("%#02x / %#02x: expected quotient %#02x remainder %#02x, got %#02x %#02x", tc.A, tc.B, tc.ExpectedA, tc.ExpectedB, A, B)
+		}
+	}
+}
+
+func TestOp0xD4(t *testing.T) {
+	cases := []struct {
+		A             byte
+		Carry         bool
+		AuxCarry      bool
+		ExpectedA     byte
+		ExpectedCarry bool
+	}{
+		{A: 0x00, ExpectedA: 0x00},
+		{A: 0x09, ExpectedA: 0x09},
+		{A: 0x0A, ExpectedA: 0x10},
+		{A: 0x99, ExpectedA: 0x99},
+		{A: 0x9A, ExpectedA: 0x00, ExpectedCarry: true},
+		{A: 0xA0, ExpectedA: 0x00, ExpectedCarry: true},
+		{A: 0xFA, ExpectedA: 0x60, ExpectedCarry: true},
+		{A: 0x10, AuxCarry: true, ExpectedA: 0x16},
+		{A: 0x10, Carry: true, ExpectedA: 0x70, ExpectedCarry: true},
+		{A: 0xBE, Carry: false, AuxCarry: false, ExpectedA: 0x24, ExpectedCarry: true}, // datasheet: 56 + 67 + 1
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, tc.A)
+		vm.WriteBit(BIT_CY, tc.Carry)
+		vm.WriteBit(BIT_AC, tc.AuxCarry)
+
+		if err := vm.Feed([]byte{0xD4}); err != nil {
+			t.Fatal(err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		carry, _ := vm.ReadBit(BIT_CY)
+
+		if A != tc.ExpectedA || carry != tc.ExpectedCarry {
+			t.Errorf("DA %#02x (CY=%t AC=%t): expected %#02x CY=%t, got %#02x CY=%t", tc.A, tc.Carry, tc.AuxCarry, tc.ExpectedA, tc.ExpectedCarry, A, carry)
+		}
+	}
+}
+
+func TestBCDAddition(t *testing.T) {
+	program := []byte{
+		0x74, 0x56, // MOV A,#56h
+		0x7B, 0x67, // MOV R3,#67h
+		0xD3, // SETB C
+		0x3B, // ADDC A,R3
+		0xD4, // DA A
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(0); err != nil {
+		t.Fatal(err)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	carry, _ := vm.ReadBit(BIT_CY)
+
+	if A != 0x24 || !carry {
+		t.Errorf("expected 56 + 67 + 1 to be BCD 124 (A=0x24, CY=1), got A=%#02x CY=%t", A, carry)
+	}
+}
+
+func TestOp0x90_0xA3(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.Feed([]byte{0x90, 0x12, 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+
+	dph, _ := vm.ReadMem(SFR_DPH)
+	dpl, _ := vm.ReadMem(SFR_DPL)
+	if dph != 0x12 || dpl != 0xFF {
+		t.Errorf("expected DPTR to be 0x12ff, got %02X%02X", dph, dpl)
+	}
+
+	if err := vm.Feed([]byte{0xA3}); err != nil {
+		t.Fatal(err)
+	}
+
+	dph, _ = vm.ReadMem(SFR_DPH)
+	dpl, _ = vm.ReadMem(SFR_DPL)
+	if dph != 0x13 || dpl != 0x00 {
+		t.Errorf("expected INC DPTR to carry into DPH (0x1300), got %02X%02X", dph, dpl)
+	}
+}
+
+func TestOp0x93(t *testing.T) {
+	program := []byte{
+		0x90, 0x00, 0x0A, // 0000: MOV DPTR,#table
+		0x74, 0x02, // 0003: MOV A,#2
+		0x93,       // 0005: MOVC A,@A+DPTR
+		0xF5, 0x30, // 0006: MOV 30h,A
+		0x80, 0xFE, // 0008: SJMP $
+		0x3F, 0x06, 0x5B, 0x4F, // 000A: table
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(10); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, _ := vm.ReadMem(0x30)
+	if actual != 0x5B {
+		t.Errorf("expected table entry 2 (0x5b), got %#02x", actual)
+	}
+}
+
+func TestOp0x83(t *testing.T) {
+	program := []byte{
+		0x74, 0x02, // 0000: MOV A,#2
+		0x83,       // 0002: MOVC A,@A+PC ; base is 0003
+		0x80, 0x02, // 0003: SJMP over
+		0xAA, 0xBB, // 0005: table (A=2 -> 0005)
+		0x80, 0xFE, // 0007: SJMP $
+	}
+
+	vm := NewMachine()
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(10); err != nil {
+		t.Fatal(err)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	if A != 0xAA {
+		t.Errorf("expected lookup relative to next instruction to read 0xaa, got %#02x", A)
+	}
+}
+
+func TestMovcOverrun(t *testing.T) {
+	cases := []struct {
+		Overrun  CodeOverrun
+		Expected byte
+		Err      bool
+	}{
+		{Overrun: CODE_OVERRUN_ERROR, Err: true},
+		{Overrun: CODE_OVERRUN_FF, Expected: 0xFF},
+		{Overrun: CODE_OVERRUN_WRAP, Expected: 0x93},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.Load([]byte{0x93})
+		vm.Code.Overrun = tc.Overrun
+		vm.SetDPTR(0x1000)
+
+		err := vm.Step()
+		if tc.Err {
+			if err == nil {
+				t.Errorf("%s: expected error for MOVC past the image, nil given", tc.Overrun)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.Overrun, err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		if A != tc.Expected {
+			t.Errorf("%s: expected A to be %#02x, got %#02x", tc.Overrun, tc.Expected, A)
+		}
+	}
+}
+
+func TestOp0xE0_0xF0(t *testing.T) {
+	vm := NewMachine()
+	vm.SetDPTR(0x1234)
+	vm.WriteMem(SFR_ACC, 0x5A)
+
+	if err := vm.Feed([]byte{0xF0}); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, _ := vm.ReadXMem(0x1234)
+	if actual != 0x5A {
+		t.Errorf("expected MOVX @DPTR,A to write 0x5a to XRAM 0x1234, got %#02x", actual)
+	}
+
+	vm.WriteMem(SFR_ACC, 0x00)
+	if err := vm.Feed([]byte{0xE0}); err != nil {
+		t.Fatal(err)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	if A != 0x5A {
+		t.Errorf("expected MOVX A,@DPTR to read 0x5a, got %#02x", A)
+	}
+
+	// IRAM at the same low address must be untouched
+	iram, _ := vm.ReadMem(0x34)
+	if iram != 0x00 {
+		t.Errorf("expected IRAM 0x34 to be untouched, got %#02x", iram)
+	}
+}
+
+func TestOp0xE2_0xE3_0xF2_0xF3(t *testing.T) {
+	cases := []struct {
+		Write  byte
+		Read   byte
+		Reg    uint8
+		BankNo byte
+	}{
+		{Write: 0xF2, Read: 0xE2, Reg: LOC_R0, BankNo: 0},
+		{Write: 0xF3, Read: 0xE3, Reg: LOC_R1, BankNo: 0},
+		{Write: 0xF2, Read: 0xE2, Reg: LOC_R0, BankNo: 2},
+		{Write: 0xF3, Read: 0xE3, Reg: LOC_R1, BankNo: 3},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.SetBankNo(tc.BankNo)
+		vm.WriteBankMem(tc.Reg, 0x40)
+		vm.WriteMem(SFR_P2, 0x07)
+		vm.WriteMem(SFR_ACC, 0xC3)
+
+		if err := vm.Feed([]byte{tc.Write}); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ := vm.ReadXMem(0x0740)
+		if actual != 0xC3 {
+			t.Errorf("%02X: expected XRAM 0x0740 (P2:Ri) to be 0xc3, got %#02x", tc.Write, actual)
+		}
+
+		vm.WriteMem(SFR_ACC, 0x00)
+		if err := vm.Feed([]byte{tc.Read}); err != nil {
+			t.Fatal(err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		if A != 0xC3 {
+			t.Errorf("%02X: expected A to be 0xc3, got %#02x", tc.Read, A)
+		}
+	}
+}
+
+func TestMovxSmallXRAM(t *testing.T) {
+	vm := NewMachine()
+	vm.XRAM, _ = NewExternalMemory(256)
+	vm.WriteMem(SFR_ACC, 0x11)
+
+	vm.SetDPTR(0x00FF)
+	if err := vm.Feed([]byte{0xF0}); err != nil {
+		t.Errorf("unexpected error writing the last XRAM byte: %s", err)
+	}
+
+	vm.SetDPTR(0x0100)
+	if err := vm.Feed([]byte{0xF0}); err == nil {
+		t.Errorf("expected error writing past the fitted XRAM, nil given")
+	}
+
+	vm.XRAM, _ = NewExternalMemory(0)
+	vm.SetDPTR(0x0000)
+	if err := vm.Feed([]byte{0xE0}); err == nil {
+		t.Errorf("expected error reading XRAM on a part without any, nil given")
+	}
+}
+
+func TestIndirectDoesNotReachSFR(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_ACC, 0x42)
+	vm.WriteBankMem(LOC_R0, SFR_ACC)
+
+	// MOV @R0,#data with R0=E0h
+	if err := vm.Feed([]byte{0x76, 0x99}); err != nil {
+		t.Fatal(err)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	if A != 0x42 {
+		t.Errorf("expected MOV @R0,#99h to leave ACC alone, got %#02x", A)
+	}
+
+	upper, _ := vm.ReadIndirect(SFR_ACC)
+	if upper != 0x99 {
+		t.Errorf("expected upper RAM E0h to be 0x99, got %#02x", upper)
+	}
+}
+
+func TestIndirectLogic(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Opcode   byte
+		Reg      uint8
+		A        byte
+		Value    byte
+		Expected byte
+	}{
+		{Name: "ORL A,@R0", Opcode: 0x46, Reg: LOC_R0, A: 0xF0, Value: 0x0F, Expected: 0xFF},
+		{Name: "ORL A,@R1", Opcode: 0x47, Reg: LOC_R1, A: 0x01, Value: 0x80, Expected: 0x81},
+		{Name: "ANL A,@R0", Opcode: 0x56, Reg: LOC_R0, A: 0xF0, Value: 0x3C, Expected: 0x30},
+		{Name: "ANL A,@R1", Opcode: 0x57, Reg: LOC_R1, A: 0xFF, Value: 0xA5, Expected: 0xA5},
+		{Name: "XRL A,@R0", Opcode: 0x66, Reg: LOC_R0, A: 0xFF, Value: 0x0F, Expected: 0xF0},
+		{Name: "XRL A,@R1", Opcode: 0x67, Reg: LOC_R1, A: 0xAA, Value: 0xAA, Expected: 0x00},
+	}
+
+	for _, tc := range cases {
+		for _, ptr := range []uint8{0x30, 0xC0} {
+			vm := NewMachine()
+			vm.WriteMem(SFR_ACC, tc.A)
+			vm.WriteBankMem(tc.Reg, ptr)
+
+			// the byte at the pointer value must be used, not the
+			// pointer itself nor the direct byte at that address
+			vm.WriteMem(ptr, 0x00)
+			vm.WriteIndirect(ptr, tc.Value)
+
+			if err := vm.Feed([]byte{tc.Opcode}); err != nil {
+				t.Fatal(err)
+			}
+
+			A, _ := vm.ReadMem(SFR_ACC)
+			if A != tc.Expected {
+				t.Errorf("%s (ptr %#02x): expected A to be %#02x, got %#02x", tc.Name, ptr, tc.Expected, A)
+			}
+		}
+	}
+}
+
+func TestStackUpperRAM(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SP, 0x7F)
+	vm.WriteMem(SFR_P0, 0x12)
+
+	if err := vm.stackPush(0xAB); err != nil {
+		t.Fatal(err)
+	}
+
+	// SP=80h must land in upper RAM, not on P0
+	p0, _ := vm.ReadMem(SFR_P0)
+	if p0 != 0x12 {
+		t.Errorf("expected push to 80h to leave P0 alone, got %#02x", p0)
+	}
+
+	val, err := vm.stackPop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if val != 0xAB {
+		t.Errorf("expected to pop 0xab, got %#02x", val)
+	}
+
+	vm.SetIRAMSize(128)
+	if err := vm.stackPush(0xAB); err == nil {
+		t.Errorf("expected error pushing past 7Fh on a 128B part, nil given")
+	}
+}
+
+func TestNewMachineResetValues(t *testing.T) {
+	vm := NewMachine()
+
+	cases := []struct {
+		Name     string
+		Loc      uint8
+		Expected byte
+	}{
+		{Name: "P0", Loc: SFR_P0, Expected: 0xFF},
+		{Name: "P1", Loc: SFR_P1, Expected: 0xFF},
+		{Name: "P2", Loc: SFR_P2, Expected: 0xFF},
+		{Name: "P3", Loc: SFR_P3, Expected: 0xFF},
+		{Name: "SP", Loc: SFR_SP, Expected: 0x07},
+		{Name: "ACC", Loc: SFR_ACC, Expected: 0x00},
+		{Name: "PSW", Loc: SFR_PSW, Expected: 0x00},
+		{Name: "IE", Loc: SFR_IE, Expected: 0x00},
+		{Name: "TCON", Loc: SFR_TCON, Expected: 0x00},
+	}
+
+	for _, tc := range cases {
+		actual, _ := vm.ReadMem(tc.Loc)
+		if actual != tc.Expected {
+			t.Errorf("expected %s to reset to %#02x, got %#02x", tc.Name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	vm := NewMachine()
+	vm.Load([]byte{0x00, 0x00, 0x00})
+	vm.PC = 0x0002
+	vm.inService = INT_LEVEL_HIGH
+	vm.WriteMem(0x30, 0xAA)
+	vm.WriteIndirect(0xC0, 0xBB)
+	vm.Data[SFR_SBUF] = 0x5A // last byte received
+	vm.WriteMem(SFR_ACC, 0x01)
+	vm.WriteMem(SFR_P1, 0x00)
+	vm.WriteMem(SFR_SP, 0x60)
+	vm.WriteXMem(0x1000, 0xCC)
+
+	vm.WarmReset()
+
+	if vm.PC != 0 || vm.inService != 0 {
+		t.Errorf("expected PC and interrupt state to be cleared, got PC=%#04x inService=%d", vm.PC, vm.inService)
+	}
+
+	if vm.ACC() != 0 || vm.PSW() != 0 || vm.P1() != 0xFF || vm.SP() != 0x07 {
+		t.Errorf("expected SFRs to take reset values, got %+v", vm.Registers())
+	}
+
+	ram, _ := vm.ReadMem(0x30)
+	upper, _ := vm.ReadIndirect(0xC0)
+	if ram != 0xAA || upper != 0xBB || vm.SBUF() != 0x5A {
+		t.Errorf("expected warm reset to keep RAM and SBUF, got %#02x, %#02x, %#02x", ram, upper, vm.SBUF())
+	}
+
+	vm.Reset()
+
+	ram, _ = vm.ReadMem(0x30)
+	upper, _ = vm.ReadIndirect(0xC0)
+	if ram != 0 || upper != 0 {
+		t.Errorf("expected power-on reset to clear RAM, got %#02x, %#02x", ram, upper)
+	}
+
+	// neither reset touches program or external memory
+	code, _ := vm.Code.Read(0)
+	xram, _ := vm.ReadXMem(0x1000)
+	if vm.Code.Size() != 3 || code != 0x00 || xram != 0xCC {
+		t.Errorf("expected code and XRAM to survive reset")
+	}
+}
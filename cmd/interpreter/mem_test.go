@@ -4,6 +4,8 @@ import (
 	"testing"
 )
 
+// SBUF is left out: writes go to the transmitter and reads
+// return the receive buffer, see TestSerialSBUF
 func TestMemWriteSFR(t *testing.T) {
 	cases := []struct {
 		RegisterName    string
@@ -12,27 +14,26 @@ func TestMemWriteSFR(t *testing.T) {
 		ReadRegDirectly func(*Machine) byte
 		ReadMem         func(*Machine) byte
 	}{
-		{RegisterName: "ACC", Location: SFR_ACC, Expected: SFR_ACC, ReadRegDirectly: func(m *Machine) byte { return m.registers.ACC }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_ACC); return val }},
-		{RegisterName: "B", Location: SFR_B, Expected: SFR_B, ReadRegDirectly: func(m *Machine) byte { return m.registers.B }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_B); return val }},
-		{RegisterName: "DPH", Location: SFR_DPH, Expected: SFR_DPH, ReadRegDirectly: func(m *Machine) byte { return m.registers.DPH }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_DPH); return val }},
-		{RegisterName: "DPL", Location: SFR_DPL, Expected: SFR_DPL, ReadRegDirectly: func(m *Machine) byte { return m.registers.DPL }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_DPL); return val }},
-		{RegisterName: "IE", Location: SFR_IE, Expected: SFR_IE, ReadRegDirectly: func(m *Machine) byte { return m.registers.IE }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_IE); return val }},
-		{RegisterName: "IP", Location: SFR_IP, Expected: SFR_IP, ReadRegDirectly: func(m *Machine) byte { return m.registers.IP }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_IP); return val }},
-		{RegisterName: "P0", Location: SFR_P0, Expected: SFR_P0, ReadRegDirectly: func(m *Machine) byte { return m.registers.P0 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P0); return val }},
-		{RegisterName: "P1", Location: SFR_P1, Expected: SFR_P1, ReadRegDirectly: func(m *Machine) byte { return m.registers.P1 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P1); return val }},
-		{RegisterName: "P2", Location: SFR_P2, Expected: SFR_P2, ReadRegDirectly: func(m *Machine) byte { return m.registers.P2 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P2); return val }},
-		{RegisterName: "P3", Location: SFR_P3, Expected: SFR_P3, ReadRegDirectly: func(m *Machine) byte { return m.registers.P3 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P3); return val }},
-		{RegisterName: "PCON", Location: SFR_PCON, Expected: SFR_PCON, ReadRegDirectly: func(m *Machine) byte { return m.registers.PCON }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_PCON); return val }},
-		{RegisterName: "PSW", Location: SFR_PSW, Expected: SFR_PSW, ReadRegDirectly: func(m *Machine) byte { return m.registers.PSW }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_PSW); return val }},
-		{RegisterName: "SCON", Location: SFR_SCON, Expected: SFR_SCON, ReadRegDirectly: func(m *Machine) byte { return m.registers.SCON }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_SCON); return val }},
-		{RegisterName: "SBUF", Location: SFR_SBUF, Expected: SFR_SBUF, ReadRegDirectly: func(m *Machine) byte { return m.registers.SBUF }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_SBUF); return val }},
-		{RegisterName: "SP", Location: SFR_SP, Expected: SFR_SP, ReadRegDirectly: func(m *Machine) byte { return m.registers.SP }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_SP); return val }},
-		{RegisterName: "TMOD", Location: SFR_TMOD, Expected: SFR_TMOD, ReadRegDirectly: func(m *Machine) byte { return m.registers.TMOD }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TMOD); return val }},
-		{RegisterName: "TCON", Location: SFR_TCON, Expected: SFR_TCON, ReadRegDirectly: func(m *Machine) byte { return m.registers.TCON }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TCON); return val }},
-		{RegisterName: "TL0", Location: SFR_TL0, Expected: SFR_TL0, ReadRegDirectly: func(m *Machine) byte { return m.registers.TL0 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TL0); return val }},
-		{RegisterName: "TH0", Location: SFR_TH0, Expected: SFR_TH0, ReadRegDirectly: func(m *Machine) byte { return m.registers.TH0 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TH0); return val }},
-		{RegisterName: "TL1", Location: SFR_TL1, Expected: SFR_TL1, ReadRegDirectly: func(m *Machine) byte { return m.registers.TL1 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TL1); return val }},
-		{RegisterName: "TH1", Location: SFR_TH1, Expected: SFR_TH1, ReadRegDirectly: func(m *Machine) byte { return m.registers.TH1 }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TH1); return val }},
+		{RegisterName: "ACC", Location: SFR_ACC, Expected: SFR_ACC, ReadRegDirectly: func(m *Machine) byte { return m.ACC() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_ACC); return val }},
+		{RegisterName: "B", Location: SFR_B, Expected: SFR_B, ReadRegDirectly: func(m *Machine) byte { return m.B() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_B); return val }},
+		{RegisterName: "DPH", Location: SFR_DPH, Expected: SFR_DPH, ReadRegDirectly: func(m *Machine) byte { return m.Registers().DPH }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_DPH); return val }},
+		{RegisterName: "DPL", Location: SFR_DPL, Expected: SFR_DPL, ReadRegDirectly: func(m *Machine) byte { return m.Registers().DPL }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_DPL); return val }},
+		{RegisterName: "IE", Location: SFR_IE, Expected: SFR_IE, ReadRegDirectly: func(m *Machine) byte { return m.IE() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_IE); return val }},
+		{RegisterName: "IP", Location: SFR_IP, Expected: SFR_IP, ReadRegDirectly: func(m *Machine) byte { return m.IP() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_IP); return val }},
+		{RegisterName: "P0", Location: SFR_P0, Expected: SFR_P0, ReadRegDirectly: func(m *Machine) byte { return m.P0() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P0); return val }},
+		{RegisterName: "P1", Location: SFR_P1, Expected: SFR_P1, ReadRegDirectly: func(m *Machine) byte { return m.P1() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P1); return val }},
+		{RegisterName: "P2", Location: SFR_P2, Expected: SFR_P2, ReadRegDirectly: func(m *Machine) byte { return m.P2() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P2); return val }},
+		{RegisterName: "P3", Location: SFR_P3, Expected: SFR_P3, ReadRegDirectly: func(m *Machine) byte { return m.P3() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_P3); return val }},
+		{RegisterName: "PCON", Location: SFR_PCON, Expected: SFR_PCON, ReadRegDirectly: func(m *Machine) byte { return m.PCON() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_PCON); return val }},
+		{RegisterName: "PSW", Location: SFR_PSW, Expected: SFR_PSW, ReadRegDirectly: func(m *Machine) byte { return m.PSW() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_PSW); return val }},
+		{RegisterName: "SCON", Location: SFR_SCON, Expected: SFR_SCON, ReadRegDirectly: func(m *Machine) byte { return m.SCON() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_SCON); return val }},
+		{RegisterName: "SP", Location: SFR_SP, Expected: SFR_SP, ReadRegDirectly: func(m *Machine) byte { return m.SP() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_SP); return val }},
+		{RegisterName: "TMOD", Location: SFR_TMOD, Expected: SFR_TMOD, ReadRegDirectly: func(m *Machine) byte { return m.TMOD() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TMOD); return val }},
+		{RegisterName: "TCON", Location: SFR_TCON, Expected: SFR_TCON, ReadRegDirectly: func(m *Machine) byte { return m.TCON() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TCON); return val }},
+		{RegisterName: "TL0", Location: SFR_TL0, Expected: SFR_TL0, ReadRegDirectly: func(m *Machine) byte { return m.TL0() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TL0); return val }},
+		{RegisterName: "TH0", Location: SFR_TH0, Expected: SFR_TH0, ReadRegDirectly: func(m *Machine) byte { return m.TH0() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TH0); return val }},
+		{RegisterName: "TL1", Location: SFR_TL1, Expected: SFR_TL1, ReadRegDirectly: func(m *Machine) byte { return m.TL1() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TL1); return val }},
+		{RegisterName: "TH1", Location: SFR_TH1, Expected: SFR_TH1, ReadRegDirectly: func(m *Machine) byte { return m.TH1() }, ReadMem: func(m *Machine) byte { val, _ := m.ReadMem(SFR_TH1); return val }},
 	}
 
 	for _, tc := range cases {
@@ -54,6 +55,30 @@ func TestMemWriteSFR(t *testing.T) {
 	}
 }
 
+func TestSFRAccessors(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.SetDPTR(0xBEEF); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.DPTR() != 0xBEEF {
+		t.Errorf("expected DPTR to be 0xbeef, got %#04x", vm.DPTR())
+	}
+
+	dph, _ := vm.ReadMem(SFR_DPH)
+	dpl, _ := vm.ReadMem(SFR_DPL)
+	if dph != 0xBE || dpl != 0xEF {
+		t.Errorf("expected DPH:DPL to be BE:EF, got %02X:%02X", dph, dpl)
+	}
+
+	// writes through memory show up in the accessors and the snapshot
+	vm.Data[SFR_B] = 0x42
+	if vm.B() != 0x42 || vm.Registers().B != 0x42 {
+		t.Errorf("expected B to be 0x42, got %#02x (snapshot %#02x)", vm.B(), vm.Registers().B)
+	}
+}
+
 func TestWriteMemAllOk(t *testing.T) {
 	vm := NewMachine()
 
@@ -139,6 +164,71 @@ func TestSetrefMem(t *testing.T) {
 	}
 }
 
+func TestIndirectUpperRAM(t *testing.T) {
+	vm := NewMachine()
+
+	if vm.IRAMSize() != 256 {
+		t.Errorf("expected default IRAM size to be 256B, got %dB", vm.IRAMSize())
+	}
+
+	vm.WriteMem(SFR_ACC, 0x11)
+	vm.WriteMem(SFR_B, 0x22)
+
+	if err := vm.WriteIndirect(SFR_ACC, 0xAA); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vm.WriteIndirect(SFR_B, 0xBB); err != nil {
+		t.Fatal(err)
+	}
+
+	A, _ := vm.ReadMem(SFR_ACC)
+	B, _ := vm.ReadMem(SFR_B)
+	if A != 0x11 || B != 0x22 {
+		t.Errorf("indirect writes must not touch SFRs, got A=%#02x B=%#02x", A, B)
+	}
+
+	upperA, _ := vm.ReadIndirect(SFR_ACC)
+	upperB, _ := vm.ReadIndirect(SFR_B)
+	if upperA != 0xAA || upperB != 0xBB {
+		t.Errorf("expected upper RAM to hold 0xaa/0xbb, got %#02x/%#02x", upperA, upperB)
+	}
+
+	// the lower 128B are shared between direct and indirect addressing
+	vm.WriteIndirect(0x7F, 0x33)
+	if val, _ := vm.ReadMem(0x7F); val != 0x33 {
+		t.Errorf("expected direct read of 0x7f to see indirect write, got %#02x", val)
+	}
+}
+
+func TestIndirect8051(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.SetIRAMSize(128); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.IRAMSize() != 128 {
+		t.Errorf("expected IRAM size to be 128B, got %dB", vm.IRAMSize())
+	}
+
+	if err := vm.WriteIndirect(0x7F, 0x01); err != nil {
+		t.Errorf("unexpected error writing 0x7f: %s", err)
+	}
+
+	if err := vm.WriteIndirect(0x80, 0x01); err == nil {
+		t.Errorf("expected error writing indirect 0x80 on a 128B part, nil given")
+	}
+
+	if _, err := vm.ReadIndirect(0xFF); err == nil {
+		t.Errorf("expected error reading indirect 0xff on a 128B part, nil given")
+	}
+
+	if err := vm.SetIRAMSize(512); err == nil {
+		t.Errorf("expected error for unsupported IRAM size, nil given")
+	}
+}
+
 func TestBankNo_BankOffset(t *testing.T) {
 	cases := []struct {
 		RS1            byte
@@ -438,3 +528,199 @@ func TestSetrefBank(t *testing.T) {
 		}
 	}
 }
+
+func TestBitLocation(t *testing.T) {
+	cases := []struct {
+		Bit          uint8
+		ExpectedLoc  uint8
+		ExpectedMask byte
+	}{
+		{Bit: 0x00, ExpectedLoc: 0x20, ExpectedMask: 0b00000001},
+		{Bit: 0x07, ExpectedLoc: 0x20, ExpectedMask: 0b10000000},
+		{Bit: 0x08, ExpectedLoc: 0x21, ExpectedMask: 0b00000001},
+		{Bit: 0x7F, ExpectedLoc: 0x2F, ExpectedMask: 0b10000000},
+		{Bit: 0x80, ExpectedLoc: SFR_P0, ExpectedMask: 0b00000001},
+		{Bit: 0x8D, ExpectedLoc: SFR_TCON, ExpectedMask: 0b00100000},
+		{Bit: 0xB5, ExpectedLoc: SFR_P3, ExpectedMask: 0b00100000},
+		{Bit: BIT_CY, ExpectedLoc: SFR_PSW, ExpectedMask: PSW_C_MASK},
+		{Bit: BIT_P, ExpectedLoc: SFR_PSW, ExpectedMask: PSW_P_MASK},
+		{Bit: 0xE7, ExpectedLoc: SFR_ACC, ExpectedMask: 0b10000000},
+		{Bit: 0xF0, ExpectedLoc: SFR_B, ExpectedMask: 0b00000001},
+	}
+
+	for _, tc := range cases {
+		loc, mask := bitLocation(tc.Bit)
+
+		if loc != tc.ExpectedLoc || mask != tc.ExpectedMask {
+			t.Errorf("bit %#02x: expected %#02x mask %#08b, got %#02x mask %#08b", tc.Bit, tc.ExpectedLoc, tc.ExpectedMask, loc, mask)
+		}
+	}
+}
+
+func TestRead_WriteBit(t *testing.T) {
+	cases := []struct {
+		Bit      uint8
+		Loc      uint8
+		Expected byte
+	}{
+		{Bit: 0x00, Loc: 0x20, Expected: 0b00000001},
+		{Bit: 0x0B, Loc: 0x21, Expected: 0b00001000},
+		{Bit: 0x7F, Loc: 0x2F, Expected: 0b10000000},
+		{Bit: 0xF7, Loc: SFR_B, Expected: 0b10000000},
+		{Bit: 0xE3, Loc: SFR_ACC, Expected: 0b00001000},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+
+		if err := vm.WriteBit(tc.Bit, true); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ := vm.ReadMem(tc.Loc)
+		if actual != tc.Expected {
+			t.Errorf("bit %#02x: expected %#02x to be %#08b, got %#08b", tc.Bit, tc.Loc, tc.Expected, actual)
+		}
+
+		set, err := vm.ReadBit(tc.Bit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !set {
+			t.Errorf("bit %#02x: expected ReadBit to return true", tc.Bit)
+		}
+
+		if err := vm.WriteBit(tc.Bit, false); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, _ = vm.ReadMem(tc.Loc)
+		if actual != 0 {
+			t.Errorf("bit %#02x: expected %#02x to be cleared, got %#08b", tc.Bit, tc.Loc, actual)
+		}
+	}
+}
+
+func TestWriteBitRegisterInSync(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.WriteBit(BIT_CY, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PSW() != PSW_C_MASK {
+		t.Errorf("expected PSW register to be %#08b, got %#08b", PSW_C_MASK, vm.PSW())
+	}
+
+	if err := vm.WriteBit(0x8C, true); err != nil { // TR0
+		t.Fatal(err)
+	}
+
+	if vm.TCON() != 0b00010000 {
+		t.Errorf("expected TCON register to be %#08b, got %#08b", 0b00010000, vm.TCON())
+	}
+}
+
+func TestParity(t *testing.T) {
+	cases := []struct {
+		A        byte
+		Expected bool
+	}{
+		{A: 0x00, Expected: false},
+		{A: 0x01, Expected: true},
+		{A: 0x03, Expected: false},
+		{A: 0x80, Expected: true},
+		{A: 0x7F, Expected: true},
+		{A: 0xFF, Expected: false},
+		{A: 0xFE, Expected: true},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, 0x01) // flip P first so stale values show up
+
+		if err := vm.WriteMem(SFR_ACC, tc.A); err != nil {
+			t.Fatal(err)
+		}
+
+		psw, _ := vm.ReadMem(SFR_PSW)
+		if PSW_P(psw) != tc.Expected {
+			t.Errorf("A=%#08b: expected P to be %t, got PSW %#08b", tc.A, tc.Expected, psw)
+		}
+	}
+}
+
+func TestParityPSWWrite(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.WriteMem(SFR_PSW, 0xFF); err != nil {
+		t.Fatal(err)
+	}
+
+	psw, _ := vm.ReadMem(SFR_PSW)
+	if psw != 0xFE {
+		t.Errorf("expected P to stay clear for A=0 when writing PSW, got %#08b", psw)
+	}
+
+	vm.WriteMem(SFR_ACC, 0x01)
+	if err := vm.WriteMem(SFR_PSW, 0x00); err != nil {
+		t.Fatal(err)
+	}
+
+	psw, _ = vm.ReadMem(SFR_PSW)
+	if psw != PSW_P_MASK {
+		t.Errorf("expected P to stay set for A=1 when writing PSW, got %#08b", psw)
+	}
+}
+
+func TestParityAccBitWrite(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.WriteBit(0xE5, true); err != nil { // ACC.5
+		t.Fatal(err)
+	}
+
+	p, _ := vm.ReadBit(BIT_P)
+	if !p {
+		t.Errorf("expected P to be set after setting ACC.5")
+	}
+
+	if err := vm.WriteBit(0xE0, true); err != nil { // ACC.0
+		t.Fatal(err)
+	}
+
+	p, _ = vm.ReadBit(BIT_P)
+	if p {
+		t.Errorf("expected P to be clear after setting ACC.0 as well")
+	}
+}
+
+func TestParityInstructions(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Instruction []byte
+		Expected    bool
+	}{
+		{Name: "INC A", Instruction: []byte{0x04}, Expected: true}, // 0x06 -> 0x07
+		{Name: "CLR A", Instruction: []byte{0xE4}, Expected: false},
+		{Name: "ADD A,#data", Instruction: []byte{0x24, 0x03}, Expected: false}, // 0x06 + 3 = 0x09
+		{Name: "RL A", Instruction: []byte{0x23}, Expected: false},
+		{Name: "SUBB A,#data", Instruction: []byte{0x94, 0x02}, Expected: true}, // 0x06 - 2 = 0x04
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_ACC, 0x06)
+
+		if err := vm.Feed(tc.Instruction); err != nil {
+			t.Fatal(err)
+		}
+
+		A, _ := vm.ReadMem(SFR_ACC)
+		p, _ := vm.ReadBit(BIT_P)
+		if p != tc.Expected {
+			t.Errorf("%s: A=%#08b: expected P to be %t", tc.Name, A, tc.Expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// An on-chip peripheral modelled outside the core, e.g. a vendor
+// watchdog or a second data pointer. Reads and writes of the SFRs it
+// claims go to it instead of Machine.Data
+type Peripheral interface {
+	// SFR addresses (0x80-0xFF) the peripheral owns, asked once
+	// when it is added
+	SFRs() []uint8
+
+	ReadSFR(m *Machine, loc uint8) (byte, error)
+	WriteSFR(m *Machine, loc uint8, value byte) error
+
+	// Called once per machine cycle, after the core's timers
+	// and serial port
+	Tick(m *Machine)
+}
+
+// Implemented by peripherals with interrupt sources of their own,
+// which are polled after the core's
+type InterruptingPeripheral interface {
+	Peripheral
+	Interrupts() []InterruptSource
+}
+
+// Implemented by peripherals that have to know about resets
+type ResettablePeripheral interface {
+	Peripheral
+	Reset(m *Machine)
+}
+
+// SFRs the core models itself, no peripheral can take these over
+var CORE_SFRS = append(slices.Clone(SFRS_8052), SFR_T2MOD)
+
+// Attaches p to the machine. Fails without attaching anything if p
+// claims an address that is not an SFR, is modelled by the core or
+// already belongs to another peripheral
+func (m *Machine) AddPeripheral(p Peripheral) error {
+	claims := p.SFRs()
+
+	for i, loc := range claims {
+		if loc < LOC_UPPER_RAM {
+			return fmt.Errorf("peripheral cannot claim %#02x, it is not an SFR address", loc)
+		}
+
+		if slices.Contains(CORE_SFRS, loc) {
+			return fmt.Errorf("peripheral cannot claim SFR %#02x, it belongs to the core", loc)
+		}
+
+		if m.sfrOwners[loc] != nil || slices.Contains(claims[:i], loc) {
+			return fmt.Errorf("peripheral cannot claim SFR %#02x, it is already claimed", loc)
+		}
+	}
+
+	for _, loc := range claims {
+		m.sfrOwners[loc] = p
+	}
+
+	m.peripherals = append(m.peripherals, p)
+
+	if ip, ok := p.(InterruptingPeripheral); ok {
+		m.interrupts = append(m.interrupts, ip.Interrupts()...)
+	}
+
+	return nil
+}
+
+func (m *Machine) tickPeripherals() {
+	for _, p := range m.peripherals {
+		p.Tick(m)
+	}
+}
+
+func (m *Machine) resetPeripherals() {
+	for _, p := range m.peripherals {
+		if rp, ok := p.(ResettablePeripheral); ok {
+			rp.Reset(m)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"testing"
+)
+
+// Counts machine cycles in CNT, sets OVF in CTRL when it wraps
+// and requests an interrupt at 0x0033 while OVF is set
+type testCounter struct {
+	count  byte
+	ctrl   byte
+	resets int
+}
+
+const TEST_CNT uint8 = 0xF9
+const TEST_CTRL uint8 = 0xF8 // bit-addressable
+const TEST_CTRL_OVF byte = (1 << 0)
+
+func (c *testCounter) SFRs() []uint8 {
+	return []uint8{TEST_CNT, TEST_CTRL}
+}
+
+func (c *testCounter) ReadSFR(m *Machine, loc uint8) (byte, error) {
+	if loc == TEST_CNT {
+		return c.count, nil
+	}
+	return c.ctrl, nil
+}
+
+func (c *testCounter) WriteSFR(m *Machine, loc uint8, value byte) error {
+	if loc == TEST_CNT {
+		c.count = value
+	} else {
+		c.ctrl = value
+	}
+	return nil
+}
+
+func (c *testCounter) Tick(m *Machine) {
+	c.count++
+	if c.count == 0 {
+		c.ctrl |= TEST_CTRL_OVF
+	}
+}
+
+func (c *testCounter) Reset(m *Machine) {
+	c.count = 0
+	c.ctrl = 0
+	c.resets++
+}
+
+func (c *testCounter) Interrupts() []InterruptSource {
+	return []InterruptSource{{
+		Name: "COUNTER", Vector: 0x0033, Enable: (1 << 6),
+		Pending:     func(m *Machine) bool { return c.ctrl&TEST_CTRL_OVF != 0 },
+		Acknowledge: func(m *Machine) { c.ctrl &= ^TEST_CTRL_OVF },
+	}}
+}
+
+func TestPeripheralSFRs(t *testing.T) {
+	vm := NewMachine()
+	counter := &testCounter{}
+
+	if err := vm.AddPeripheral(counter); err != nil {
+		t.Fatal(err)
+	}
+
+	// MOV 0F9h,#10h
+	if err := vm.Feed([]byte{0x75, TEST_CNT, 0x10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the write lands before the 2 cycles of the MOV tick the counter
+	if counter.count != 0x12 {
+		t.Errorf("expected the counter at 0x12, got %#02x", counter.count)
+	}
+
+	// MOV A,0F9h
+	if err := vm.Feed([]byte{0xE5, TEST_CNT}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.ACC() != 0x12 {
+		t.Errorf("expected MOV A to read the counter (0x12), got %#02x", vm.ACC())
+	}
+
+	// SETB 0F8h.1 goes through the peripheral too
+	if err := vm.Feed([]byte{0xD2, TEST_CTRL + 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if counter.ctrl != 0x02 {
+		t.Errorf("expected SETB to reach the peripheral, CTRL is %#02x", counter.ctrl)
+	}
+
+	if vm.Data[TEST_CNT] != 0 || vm.Data[TEST_CTRL] != 0 {
+		t.Errorf("expected claimed SFRs to stay out of Data")
+	}
+
+	vm.WarmReset()
+	if counter.resets != 1 || counter.ctrl != 0 {
+		t.Errorf("expected the reset to reach the peripheral, got %d resets, CTRL %#02x", counter.resets, counter.ctrl)
+	}
+}
+
+func TestPeripheralInterrupt(t *testing.T) {
+	vm := nopProgram(t)
+	counter := &testCounter{count: 0xFE}
+
+	if err := vm.AddPeripheral(counter); err != nil {
+		t.Fatal(err)
+	}
+
+	vm.WriteMem(SFR_IE, IE_EA_MASK|(1<<6))
+
+	for i := 0; i < 2; i++ {
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if vm.PC != 0x0033 {
+		t.Errorf("expected the counter to interrupt at 0x0033, PC is %#04x", vm.PC)
+	}
+
+	if counter.ctrl&TEST_CTRL_OVF != 0 {
+		t.Errorf("expected OVF to be acknowledged")
+	}
+}
+
+func TestPeripheralClaims(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Claim []uint8
+	}{
+		{Name: "RAM", Claim: []uint8{0x30}},
+		{Name: "core SFR", Claim: []uint8{SFR_TCON}},
+		{Name: "timer 2", Claim: []uint8{SFR_T2MOD}},
+		{Name: "claimed twice", Claim: []uint8{0xF1, 0xF1}},
+		{Name: "already claimed", Claim: []uint8{0xF2, TEST_CNT}},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		if err := vm.AddPeripheral(&testCounter{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := vm.AddPeripheral(claimer(tc.Claim)); err == nil {
+			t.Errorf("%s: expected the claim to fail", tc.Name)
+		}
+
+		// a failed claim attaches nothing
+		if len(vm.peripherals) != 1 || vm.sfrOwners[0xF1] != nil || vm.sfrOwners[0xF2] != nil {
+			t.Errorf("%s: expected nothing to be attached", tc.Name)
+		}
+	}
+}
+
+// A peripheral that only claims addresses
+type claimer []uint8
+
+func (c claimer) SFRs() []uint8                                    { return c }
+func (c claimer) ReadSFR(m *Machine, loc uint8) (byte, error)      { return 0, nil }
+func (c claimer) WriteSFR(m *Machine, loc uint8, value byte) error { return nil }
+func (c claimer) Tick(m *Machine)                                  {}
+
+func TestPeripheralOnDevice(t *testing.T) {
+	vm, err := NewMachineFor(DEVICE_8051)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the 8051 has no SFR at 0F9h until a peripheral brings one
+	if _, err := vm.ReadMem(TEST_CNT); err == nil {
+		t.Fatalf("expected 0F9h to be unimplemented on an 8051")
+	}
+
+	if err := vm.AddPeripheral(&testCounter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.ReadMem(TEST_CNT); err != nil {
+		t.Errorf("expected the peripheral to provide 0F9h, got %s", err)
+	}
+}
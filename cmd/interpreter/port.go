@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const PORT_COUNT int = 4
+
+// Port latches, indexed by port number
+var PORT_SFRS = [PORT_COUNT]uint8{SFR_P0, SFR_P1, SFR_P2, SFR_P3}
+
+// Level of a port pin as seen from outside the chip
+type PinState int
+
+const (
+	PIN_LOW      PinState = iota
+	PIN_HIGH              // driven high, or pulled up
+	PIN_FLOATING          // P0 with a 1 in its latch and nothing driving it
+)
+
+func (s PinState) String() string {
+	switch s {
+	case PIN_LOW:
+		return "low"
+	case PIN_HIGH:
+		return "high"
+	case PIN_FLOATING:
+		return "floating"
+	}
+
+	return fmt.Sprintf("PinState(%d)", int(s))
+}
+
+// Called whenever the level of a pin changes, whoever caused it
+type PinChangeFunc func(port int, bit int, state PinState)
+
+// What the outside world does to a port: pins in Driven are forced
+// to the level in Level, the others are left to the chip
+type portDrive struct {
+	Driven byte
+	Level  byte
+}
+
+func portIndex(loc uint8) (int, bool) {
+	for port, sfr := range PORT_SFRS {
+		if sfr == loc {
+			return port, true
+		}
+	}
+
+	return 0, false
+}
+
+func checkPin(port int, bit int) error {
+	if port < 0 || port >= PORT_COUNT {
+		return fmt.Errorf("port %d does not exist, expected 0-%d", port, PORT_COUNT-1)
+	}
+
+	if bit < 0 || bit > 7 {
+		return fmt.Errorf("pin %d does not exist, expected 0-7", bit)
+	}
+
+	return nil
+}
+
+// State of a single pin. A 0 in the latch turns on the pull-down, which
+// wins over anything outside. With a 1, P1-P3 are weakly pulled up so an
+// external driver decides, while P0 is open-drain and floats
+func (m *Machine) pinState(port int, bit int) PinState {
+	mask := byte(1) << bit
+	latch := m.Data[PORT_SFRS[port]]
+	drive := m.ports[port]
+
+	if latch&mask == 0 {
+		return PIN_LOW
+	}
+
+	if drive.Driven&mask != 0 {
+		if drive.Level&mask != 0 {
+			return PIN_HIGH
+		}
+		return PIN_LOW
+	}
+
+	if port == 0 {
+		return PIN_FLOATING
+	}
+	return PIN_HIGH
+}
+
+// What the CPU reads from the port pins. A floating P0 pin reads as 1
+func (m *Machine) portPins(port int) byte {
+	var pins byte
+	for bit := 0; bit < 8; bit++ {
+		if m.pinState(port, bit) != PIN_LOW {
+			pins |= 1 << bit
+		}
+	}
+
+	return pins
+}
+
+// Read-modify-write instructions (ANL, ORL, XRL, INC, DEC, DJNZ, JBC,
+// CPL/CLR/SETB and MOV on port bits) read the port latch, not the pins,
+// so a pin held low from outside does not get its latch cleared
+func (m *Machine) readLatch(loc uint8) (byte, error) {
+	if _, ok := portIndex(loc); ok {
+		return m.Data[loc], nil
+	}
+
+	return m.ReadMem(loc)
+}
+
+func (m *Machine) readLatchBit(bit uint8) (bool, error) {
+	loc, mask := bitLocation(bit)
+
+	val, err := m.readLatch(loc)
+	if err != nil {
+		return false, fmt.Errorf("failed to read bit %#02x: %s", bit, err)
+	}
+
+	return val&mask == mask, nil
+}
+
+// Current state of a pin, e.g. to light an LED
+func (m *Machine) Pin(port int, bit int) (PinState, error) {
+	if err := checkPin(port, bit); err != nil {
+		return PIN_LOW, err
+	}
+
+	return m.pinState(port, bit), nil
+}
+
+// Drives a pin from outside the chip, e.g. a button pulling it low
+func (m *Machine) DrivePin(port int, bit int, high bool) error {
+	if err := checkPin(port, bit); err != nil {
+		return err
+	}
+
+	m.drivePins(port, 1<<bit, high)
+	return nil
+}
+
+// Stops driving a pin, leaving it to the chip (and the pull-up on P1-P3)
+func (m *Machine) ReleasePin(port int, bit int) error {
+	if err := checkPin(port, bit); err != nil {
+		return err
+	}
+
+	m.updatePins(port, func() {
+		m.ports[port].Driven &= ^(byte(1) << bit)
+	})
+	return nil
+}
+
+// Registers fn to be called on every pin change, in registration order
+func (m *Machine) OnPinChange(fn PinChangeFunc) {
+	m.pinWatchers = append(m.pinWatchers, fn)
+}
+
+func (m *Machine) drivePins(port int, mask byte, high bool) {
+	m.updatePins(port, func() {
+		m.ports[port].Driven |= mask
+		if high {
+			m.ports[port].Level |= mask
+		} else {
+			m.ports[port].Level &= ^mask
+		}
+	})
+}
+
+// Applies change to a port and tells the watchers about any pin
+// that ended up at a different level
+func (m *Machine) updatePins(port int, change func()) {
+	var before [8]PinState
+	for bit := range before {
+		before[bit] = m.pinState(port, bit)
+	}
+
+	change()
+
+	for bit := range before {
+		if after := m.pinState(port, bit); after != before[bit] {
+			for _, fn := range m.pinWatchers {
+				fn(port, bit, after)
+			}
+		}
+	}
+}
+
+// Port 3 alternate functions
+const P3_INT0_MASK byte = (1 << 2)
+const P3_INT1_MASK byte = (1 << 3)
+const P3_T0_MASK byte = (1 << 4)
+const P3_T1_MASK byte = (1 << 5)
+
+func (m *Machine) p1Pin(mask byte) bool {
+	return m.pinState(1, bits.TrailingZeros8(mask)) != PIN_LOW
+}
+
+func (m *Machine) p3Pin(mask byte) bool {
+	return m.pinState(3, bits.TrailingZeros8(mask)) != PIN_LOW
+}
+
+func (m *Machine) setP3Input(mask byte, high bool) {
+	m.drivePins(3, mask, high)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPortPinState(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Port     int
+		Latch    byte
+		Drive    func(vm *Machine)
+		Expected PinState
+	}{
+		{Name: "P1 pulled up", Port: 1, Latch: 0xFF, Expected: PIN_HIGH},
+		{Name: "P1 latch low", Port: 1, Latch: 0xFE, Expected: PIN_LOW},
+		{Name: "P1 driven low", Port: 1, Latch: 0xFF, Drive: func(vm *Machine) { vm.DrivePin(1, 0, false) }, Expected: PIN_LOW},
+		{Name: "P1 latch low wins", Port: 1, Latch: 0xFE, Drive: func(vm *Machine) { vm.DrivePin(1, 0, true) }, Expected: PIN_LOW},
+		{Name: "P1 released", Port: 1, Latch: 0xFF, Drive: func(vm *Machine) { vm.DrivePin(1, 0, false); vm.ReleasePin(1, 0) }, Expected: PIN_HIGH},
+		{Name: "P0 floating", Port: 0, Latch: 0xFF, Expected: PIN_FLOATING},
+		{Name: "P0 driven high", Port: 0, Latch: 0xFF, Drive: func(vm *Machine) { vm.DrivePin(0, 0, true) }, Expected: PIN_HIGH},
+		{Name: "P0 latch low", Port: 0, Latch: 0x00, Expected: PIN_LOW},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(PORT_SFRS[tc.Port], tc.Latch)
+		if tc.Drive != nil {
+			tc.Drive(vm)
+		}
+
+		state, err := vm.Pin(tc.Port, 0)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		if state != tc.Expected {
+			t.Errorf("%s: expected pin to be %s, got %s", tc.Name, tc.Expected, state)
+		}
+	}
+}
+
+func TestPortReadsPins(t *testing.T) {
+	vm := NewMachine()
+	vm.DrivePin(1, 3, false)
+
+	// MOV A, P1
+	if err := vm.Feed([]byte{0xE5, SFR_P1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.ACC() != 0xF7 {
+		t.Errorf("expected MOV A,P1 to read the pins (0xF7), got %#02x", vm.ACC())
+	}
+
+	// a floating P0 pin reads as 1
+	p0, _ := vm.ReadMem(SFR_P0)
+	if p0 != 0xFF {
+		t.Errorf("expected floating P0 to read 0xFF, got %#02x", p0)
+	}
+}
+
+func TestPortReadModifyWrite(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Instr    []byte
+		Expected byte // P1 latch afterwards
+	}{
+		{Name: "ANL P1,#data", Instr: []byte{0x53, SFR_P1, 0xFE}, Expected: 0xFE},
+		{Name: "ORL P1,#data", Instr: []byte{0x43, SFR_P1, 0x00}, Expected: 0xFF},
+		{Name: "XRL P1,#data", Instr: []byte{0x63, SFR_P1, 0x01}, Expected: 0xFE},
+		{Name: "CPL P1.0", Instr: []byte{0xB2, 0x90}, Expected: 0xFE},
+		{Name: "CLR P1.0", Instr: []byte{0xC2, 0x90}, Expected: 0xFE},
+		{Name: "CPL P1.3", Instr: []byte{0xB2, 0x93}, Expected: 0xF7}, // complements the latch, not the low pin
+		{Name: "INC P1", Instr: []byte{0x05, SFR_P1}, Expected: 0x00},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+
+		// a button holds P1.3 low, the latch must keep its 1
+		vm.DrivePin(1, 3, false)
+
+		if err := vm.Feed(tc.Instr); err != nil {
+			t.Fatalf("%s: %s", tc.Name, err)
+		}
+
+		if vm.P1() != tc.Expected {
+			t.Errorf("%s: expected the P1 latch to be %#02x, got %#02x", tc.Name, tc.Expected, vm.P1())
+		}
+
+		if tc.Expected&0x08 == 0 {
+			continue
+		}
+
+		vm.ReleasePin(1, 3)
+		if state, _ := vm.Pin(1, 3); state != PIN_HIGH {
+			t.Errorf("%s: expected P1.3 to go high once released, got %s", tc.Name, state)
+		}
+	}
+}
+
+func TestPortJBCReadsLatch(t *testing.T) {
+	vm := NewMachine()
+	vm.DrivePin(1, 0, false)
+
+	// JBC P1.0, +2 - the latch is set even though the pin is low
+	if err := vm.Feed([]byte{0x10, 0x90, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x05 {
+		t.Errorf("expected JBC to jump to 0x05, got %#04x", vm.PC)
+	}
+
+	if vm.P1()&0x01 != 0 {
+		t.Errorf("expected JBC to clear the P1.0 latch, got %#02x", vm.P1())
+	}
+}
+
+func TestPortChangeCallbacks(t *testing.T) {
+	type change struct {
+		Port, Bit int
+		State     PinState
+	}
+
+	vm := NewMachine()
+
+	var changes []change
+	vm.OnPinChange(func(port int, bit int, state PinState) {
+		changes = append(changes, change{port, bit, state})
+	})
+
+	// CPU: CLR P1.2, then SETB P1.2
+	if err := vm.Feed([]byte{0xC2, 0x92}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Feed([]byte{0xD2, 0x92}); err != nil {
+		t.Fatal(err)
+	}
+
+	// host: drive P3.4 low, then low again (no change)
+	vm.DrivePin(3, 4, false)
+	vm.DrivePin(3, 4, false)
+
+	// P0 goes from floating to driven
+	vm.DrivePin(0, 7, true)
+
+	// writing the same latch value changes nothing
+	vm.WriteMem(SFR_P2, 0xFF)
+
+	expected := []change{
+		{1, 2, PIN_LOW},
+		{1, 2, PIN_HIGH},
+		{3, 4, PIN_LOW},
+		{0, 7, PIN_HIGH},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+
+	for i, c := range changes {
+		if c != expected[i] {
+			t.Errorf("change %d: expected %v, got %v", i, expected[i], c)
+		}
+	}
+}
+
+func TestSchedulePin(t *testing.T) {
+	vm := NewMachine()
+
+	if err := vm.SchedulePin(2, 1, 5, false); err != nil {
+		t.Fatal(err)
+	}
+
+	feedNops(t, vm, 1)
+	if state, _ := vm.Pin(1, 5); state != PIN_HIGH {
+		t.Errorf("expected P1.5 to still be high after 1 cycle, got %s", state)
+	}
+
+	feedNops(t, vm, 1)
+	if state, _ := vm.Pin(1, 5); state != PIN_LOW {
+		t.Errorf("expected P1.5 to be low after 2 cycles, got %s", state)
+	}
+}
+
+func TestPinInvalid(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Port, Bit int
+	}{
+		{Name: "negative port", Port: -1, Bit: 0},
+		{Name: "port 4", Port: 4, Bit: 0},
+		{Name: "bit 8", Port: 1, Bit: 8},
+		{Name: "negative bit", Port: 1, Bit: -1},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+
+		if _, err := vm.Pin(tc.Port, tc.Bit); err == nil {
+			t.Errorf("%s: expected Pin to fail", tc.Name)
+		}
+
+		if err := vm.DrivePin(tc.Port, tc.Bit, true); err == nil {
+			t.Errorf("%s: expected DrivePin to fail", tc.Name)
+		}
+
+		if err := vm.ReleasePin(tc.Port, tc.Bit); err == nil {
+			t.Errorf("%s: expected ReleasePin to fail", tc.Name)
+		}
+
+		if err := vm.SchedulePin(0, tc.Port, tc.Bit, true); err == nil {
+			t.Errorf("%s: expected SchedulePin to fail", tc.Name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+)
+
+// PCON bits, SMOD lives with the serial port
+const PCON_GF1_MASK byte = (1 << 3)
+const PCON_GF0_MASK byte = (1 << 2)
+const PCON_PD_MASK byte = (1 << 1)
+const PCON_IDL_MASK byte = (1 << 0)
+
+type PowerState int
+
+const (
+	POWER_NORMAL PowerState = iota
+	POWER_IDLE              // CPU stopped, timers, serial port and interrupts running
+	POWER_DOWN              // oscillator stopped, only a reset gets it going again
+)
+
+func (s PowerState) String() string {
+	switch s {
+	case POWER_NORMAL:
+		return "normal"
+	case POWER_IDLE:
+		return "idle"
+	case POWER_DOWN:
+		return "power down"
+	}
+
+	return fmt.Sprintf("PowerState(%d)", int(s))
+}
+
+// Power mode selected by PCON. PD wins when both bits are set
+func (m *Machine) PowerState() PowerState {
+	pcon := m.Data[SFR_PCON]
+
+	if pcon&PCON_PD_MASK != 0 {
+		return POWER_DOWN
+	}
+
+	if pcon&PCON_IDL_MASK != 0 {
+		return POWER_IDLE
+	}
+
+	return POWER_NORMAL
+}
+
+// One machine cycle in idle mode: no instruction is fetched but the
+// peripherals keep running, and any interrupt taken ends idle mode
+// (see vectorInterrupt)
+func (m *Machine) idleCycle() error {
+	m.holdInterrupts = false
+	m.tick(1)
+
+	if _, err := m.pollInterrupts(); err != nil {
+		return fmt.Errorf("interrupt error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"testing"
+)
+
+// ORL PCON,#mode followed by NOPs up to past the vectors
+func powerProgram(t *testing.T, mode byte) *Machine {
+	t.Helper()
+
+	prog := make([]byte, 0x40)
+	copy(prog, []byte{0x43, SFR_PCON, mode})
+
+	vm := NewMachine()
+	if err := vm.Load(prog); err != nil {
+		t.Fatal(err)
+	}
+
+	return vm
+}
+
+func TestPowerState(t *testing.T) {
+	cases := []struct {
+		Name     string
+		PCON     byte
+		Expected PowerState
+	}{
+		{Name: "normal", PCON: 0x00, Expected: POWER_NORMAL},
+		{Name: "SMOD and flags only", PCON: PCON_SMOD_MASK | PCON_GF0_MASK | PCON_GF1_MASK, Expected: POWER_NORMAL},
+		{Name: "idle", PCON: PCON_IDL_MASK, Expected: POWER_IDLE},
+		{Name: "power down", PCON: PCON_PD_MASK, Expected: POWER_DOWN},
+		{Name: "power down wins", PCON: PCON_PD_MASK | PCON_IDL_MASK, Expected: POWER_DOWN},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_PCON, tc.PCON)
+
+		if state := vm.PowerState(); state != tc.Expected {
+			t.Errorf("%s: expected %s, got %s", tc.Name, tc.Expected, state)
+		}
+	}
+}
+
+func TestIdleWakesOnInterrupt(t *testing.T) {
+	vm := powerProgram(t, PCON_IDL_MASK)
+	vm.WriteMem(SFR_TMOD, 0x01)
+	vm.WriteMem(SFR_TL0, 0xF0)
+	vm.WriteMem(SFR_TH0, 0xFF)
+	vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+	vm.WriteMem(SFR_IE, IE_EA_MASK|IE_ET0_MASK)
+
+	if err := vm.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PowerState() != POWER_IDLE {
+		t.Fatalf("expected the machine to be idle, got %s", vm.PowerState())
+	}
+
+	// ORL took 2 cycles, TL0 is at F2h: 13 more idle cycles to the overflow
+	for i := 0; i < 13; i++ {
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+
+		if vm.PC != 0x0003 {
+			t.Fatalf("expected no fetch while idle, PC moved to %#04x after %d cycles", vm.PC, i+1)
+		}
+	}
+
+	if vm.TL0() != 0xFF {
+		t.Errorf("expected the timer to keep counting while idle (TL0 0xFF), got %#02x", vm.TL0())
+	}
+
+	if err := vm.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.PC != 0x000B {
+		t.Errorf("expected the timer interrupt to vector to 0x000B, got %#04x", vm.PC)
+	}
+
+	if vm.PowerState() != POWER_NORMAL {
+		t.Errorf("expected the interrupt to end idle mode, got %s", vm.PowerState())
+	}
+
+	// RETI comes back to the instruction after the one that set IDL
+	lo, _ := vm.ReadIndirect(0x08)
+	hi, _ := vm.ReadIndirect(0x09)
+	if lo != 0x03 || hi != 0x00 {
+		t.Errorf("expected return address 0x0003 on the stack, got %02X%02X", hi, lo)
+	}
+}
+
+func TestIdleWakesOnExternalInterrupt(t *testing.T) {
+	vm := powerProgram(t, PCON_IDL_MASK)
+	vm.WriteMem(SFR_IE, IE_EA_MASK|IE_EX0_MASK)
+	vm.ScheduleINT0(10, false)
+
+	if _, err := vm.Run(100); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ISR is all NOPs, so Run just carries on from the vector
+	if vm.PC < 0x0003 || vm.PowerState() != POWER_NORMAL {
+		t.Errorf("expected INT0 to wake the machine, PC %#04x in %s mode", vm.PC, vm.PowerState())
+	}
+
+	if vm.Cycles() < 10 {
+		t.Errorf("expected to idle until INT0 at cycle 10, woke at %d", vm.Cycles())
+	}
+}
+
+func TestRunStopsIdleWithoutInterrupts(t *testing.T) {
+	vm := powerProgram(t, PCON_IDL_MASK)
+
+	executed, err := vm.Run(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 1 || vm.PC != 0x0003 {
+		t.Errorf("expected Run to stop after entering idle, ran %d to %#04x", executed, vm.PC)
+	}
+}
+
+func TestPowerDown(t *testing.T) {
+	vm := powerProgram(t, PCON_PD_MASK)
+	vm.WriteMem(SFR_TMOD, 0x01)
+	vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+	vm.WriteMem(SFR_IE, IE_EA_MASK|IE_EX0_MASK|IE_ET0_MASK)
+
+	if err := vm.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := vm.Cycles()
+	tl0 := vm.TL0()
+
+	// not even an interrupt gets it out of power down
+	vm.SetINT0(false)
+	for i := 0; i < 10; i++ {
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executed, err := vm.Run(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 0 || vm.PC != 0x0003 || vm.Cycles() != cycles || vm.TL0() != tl0 {
+		t.Errorf("expected the machine to be frozen, ran %d to %#04x, %d cycles, TL0 %#02x", executed, vm.PC, vm.Cycles(), vm.TL0())
+	}
+
+	vm.WarmReset()
+	if vm.PowerState() != POWER_NORMAL || vm.PC != 0 {
+		t.Errorf("expected reset to leave power down, got %s at %#04x", vm.PowerState(), vm.PC)
+	}
+}
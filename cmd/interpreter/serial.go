@@ -0,0 +1,297 @@
+package main
+
+import (
+	"io"
+	"log"
+)
+
+// SCON bits
+const SCON_SM0_MASK byte = (1 << 7)
+const SCON_SM1_MASK byte = (1 << 6)
+const SCON_SM2_MASK byte = (1 << 5)
+const SCON_REN_MASK byte = (1 << 4)
+const SCON_TB8_MASK byte = (1 << 3)
+const SCON_RB8_MASK byte = (1 << 2)
+const SCON_TI_MASK byte = (1 << 1)
+const SCON_RI_MASK byte = (1 << 0)
+
+// PCON bits
+const PCON_SMOD_MASK byte = (1 << 7)
+
+const SERIAL_MODE_SHIFT byte = 0      // 8-bit shift register, fosc/12
+const SERIAL_MODE_8BIT_UART byte = 1  // 10-bit frame, timer 1 baud rate
+const SERIAL_MODE_9BIT_FIXED byte = 2 // 11-bit frame, fosc/64 or fosc/32
+const SERIAL_MODE_9BIT_UART byte = 3  // 11-bit frame, timer 1 baud rate
+
+// how many bytes from the host reader may wait for the firmware
+const SERIAL_INPUT_BUFFER int = 256
+
+// A frame on the serial line: 8 data bits plus the 9th bit in modes 2
+// and 3 (the stop bit in mode 1)
+type SerialFrame struct {
+	Data byte
+	Bit9 bool
+}
+
+type serialPort struct {
+	out     io.Writer     // where transmitted bytes go, may be nil
+	in      chan byte     // bytes read from the host reader
+	pending []SerialFrame // frames waiting to arrive on RXD
+	tx      serialShift
+	rx      serialShift
+}
+
+// A frame being shifted in or out. Remaining is counted in the unit the
+// frame's baud rate is derived from: oscillator periods in modes 0 and 2,
+// timer 1 or timer 2 overflows in modes 1 and 3
+type serialShift struct {
+	Busy      bool
+	Frame     SerialFrame
+	Mode      byte
+	Timer2    bool // clocked by timer 2 (RCLK/TCLK)
+	Remaining int
+}
+
+func serialMode(scon byte) byte {
+	return scon >> 6
+}
+
+// Connects the serial port to the host: every transmitted byte is written
+// to w and bytes read from r are received one frame at a time (with the
+// 9th/stop bit set). Either may be nil. r is drained by a goroutine
+// until it returns an error, io.EOF included, so a byte from r starts
+// arriving at whichever cycle the receiver is idle once the goroutine has
+// read it, not at a defined point in simulated time. Use QueueSerialFrame
+// where reception has to be reproducible
+func (m *Machine) AttachSerial(r io.Reader, w io.Writer) {
+	m.serial.out = w
+
+	if r == nil {
+		m.serial.in = nil
+		return
+	}
+
+	in := make(chan byte, SERIAL_INPUT_BUFFER)
+	m.serial.in = in
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				in <- buf[0]
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("serial input stopped: %s", err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Puts a frame on the receive line, e.g. an address frame with Bit9 set
+// for multiprocessor communication
+func (m *Machine) QueueSerialFrame(data byte, bit9 bool) {
+	m.serial.pending = append(m.serial.pending, SerialFrame{Data: data, Bit9: bit9})
+}
+
+// Baud rate the serial port currently transmits at. In modes 1 and 3
+// this assumes timer 1 runs as an 8-bit auto-reload timer, or timer 2
+// as a baud rate generator off the oscillator (0 if it does not)
+func (m *Machine) SerialBaudRate() float64 {
+	scon := m.Data[SFR_SCON]
+	smod := m.Data[SFR_PCON]&PCON_SMOD_MASK != 0
+	osc := float64(m.ClockHz)
+
+	switch serialMode(scon) {
+	case SERIAL_MODE_SHIFT:
+		return osc / 12
+	case SERIAL_MODE_9BIT_FIXED:
+		if smod {
+			return osc / 32
+		}
+		return osc / 64
+	}
+
+	if t2con := m.Data[SFR_T2CON]; t2con&T2CON_TCLK_MASK != 0 {
+		if t2con&T2CON_CT2_MASK != 0 {
+			return 0
+		}
+		return osc / 32 / float64(0x10000-int(m.rcap2()))
+	}
+
+	tmod := m.Data[SFR_TMOD] >> 4
+	if tmod&TMOD_MODE_MASK != TIMER_MODE_AUTO_RELOAD || tmod&TMOD_CT_MASK != 0 {
+		return 0
+	}
+
+	overflows := osc / float64(m.timerDivider(CKCON_T1M_MASK)) / float64(256-int(m.Data[SFR_TH1]))
+	if smod {
+		return overflows / 16
+	}
+	return overflows / 32
+}
+
+// Length of a whole frame in the mode's baud rate unit. Timer 2 takes
+// 16 overflows per bit whatever SMOD says
+func (m *Machine) serialFrameLength(mode byte, timer2 bool) int {
+	smod := m.Data[SFR_PCON]&PCON_SMOD_MASK != 0
+
+	if timer2 {
+		if mode == SERIAL_MODE_8BIT_UART {
+			return 10 * 16
+		}
+		return 11 * 16
+	}
+
+	switch mode {
+	case SERIAL_MODE_SHIFT:
+		return 8 * 12
+	case SERIAL_MODE_8BIT_UART:
+		if smod {
+			return 10 * 16
+		}
+		return 10 * 32
+	case SERIAL_MODE_9BIT_FIXED:
+		if smod {
+			return 11 * 32
+		}
+		return 11 * 64
+	}
+
+	if smod {
+		return 11 * 16
+	}
+	return 11 * 32
+}
+
+// Any write to SBUF starts a transmission. SBUF itself (Data[SFR_SBUF])
+// is the receive buffer, the byte written only goes to the shift register
+func (m *Machine) serialTransmit(value byte) {
+	scon := m.Data[SFR_SCON]
+	mode := serialMode(scon)
+	timer2 := m.serialTimer2(mode, T2CON_TCLK_MASK)
+
+	m.serial.tx = serialShift{
+		Busy:      true,
+		Frame:     SerialFrame{Data: value, Bit9: scon&SCON_TB8_MASK != 0 || mode == SERIAL_MODE_8BIT_UART},
+		Mode:      mode,
+		Timer2:    timer2,
+		Remaining: m.serialFrameLength(mode, timer2),
+	}
+}
+
+// Whether the timer 2 clock selected by RCLK or TCLK drives a frame
+// in the given mode, only modes 1 and 3 take their baud rate from a timer
+func (m *Machine) serialTimer2(mode byte, clk byte) bool {
+	if mode != SERIAL_MODE_8BIT_UART && mode != SERIAL_MODE_9BIT_UART {
+		return false
+	}
+
+	return m.Data[SFR_T2CON]&clk != 0
+}
+
+// Advances the serial port by one machine cycle
+func (m *Machine) tickSerial(t1Overflows int, t2Overflows int) {
+	if m.serial.tx.Busy && m.serialAdvance(&m.serial.tx, t1Overflows, t2Overflows) {
+		m.serial.tx.Busy = false
+		m.Data[SFR_SCON] |= SCON_TI_MASK
+
+		if m.serial.out != nil {
+			if _, err := m.serial.out.Write([]byte{m.serial.tx.Frame.Data}); err != nil {
+				log.Printf("serial output failed: %s", err)
+			}
+		}
+	}
+
+	if !m.serial.rx.Busy {
+		m.serialStartReceive()
+	}
+
+	if m.serial.rx.Busy && m.serialAdvance(&m.serial.rx, t1Overflows, t2Overflows) {
+		m.serial.rx.Busy = false
+		m.serialReceived(m.serial.rx.Frame, m.serial.rx.Mode)
+	}
+}
+
+// @return bool - whether the frame is complete
+func (m *Machine) serialAdvance(shift *serialShift, t1Overflows int, t2Overflows int) bool {
+	switch {
+	case shift.Mode == SERIAL_MODE_SHIFT || shift.Mode == SERIAL_MODE_9BIT_FIXED:
+		shift.Remaining -= m.ClocksPerCycle
+	case shift.Timer2:
+		shift.Remaining -= t2Overflows
+	default:
+		shift.Remaining -= t1Overflows
+	}
+
+	return shift.Remaining <= 0
+}
+
+func (m *Machine) serialStartReceive() {
+	scon := m.Data[SFR_SCON]
+	if scon&SCON_REN_MASK == 0 {
+		return
+	}
+
+	// mode 0 only shifts in a byte once RI has been cleared
+	mode := serialMode(scon)
+	if mode == SERIAL_MODE_SHIFT && scon&SCON_RI_MASK != 0 {
+		return
+	}
+
+	var frame SerialFrame
+	if len(m.serial.pending) > 0 {
+		frame = m.serial.pending[0]
+		m.serial.pending = m.serial.pending[1:]
+	} else {
+		select {
+		case b := <-m.serial.in:
+			frame = SerialFrame{Data: b, Bit9: true}
+		default:
+			return
+		}
+	}
+
+	timer2 := m.serialTimer2(mode, T2CON_RCLK_MASK)
+
+	m.serial.rx = serialShift{
+		Busy:      true,
+		Frame:     frame,
+		Mode:      mode,
+		Timer2:    timer2,
+		Remaining: m.serialFrameLength(mode, timer2),
+	}
+}
+
+// Loads a complete frame into SBUF, unless RI is still set from the last
+// one or SM2 filters it out, in which case the frame is lost as on
+// the real chip
+func (m *Machine) serialReceived(frame SerialFrame, mode byte) {
+	scon := m.Data[SFR_SCON]
+
+	if mode != SERIAL_MODE_SHIFT {
+		if scon&SCON_RI_MASK != 0 {
+			log.Printf("serial overrun, frame %#02x lost", frame.Data)
+			return
+		}
+
+		// in modes 2 and 3 SM2 only lets address frames (9th bit set)
+		// through, in mode 1 only frames with a valid stop bit
+		if scon&SCON_SM2_MASK != 0 && !frame.Bit9 {
+			return
+		}
+
+		if frame.Bit9 {
+			scon |= SCON_RB8_MASK
+		} else {
+			scon &= ^SCON_RB8_MASK
+		}
+	}
+
+	m.Data[SFR_SBUF] = frame.Data
+	m.Data[SFR_SCON] = scon | SCON_RI_MASK
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSerialSBUF(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SCON, 0x80) // mode 2, fosc/64
+	vm.Data[SFR_SBUF] = 0x11    // last byte received
+
+	// MOV SBUF,#55h
+	if err := vm.Feed([]byte{0x75, SFR_SBUF, 0x55}); err != nil {
+		t.Fatal(err)
+	}
+
+	sbuf, _ := vm.ReadMem(SFR_SBUF)
+	if sbuf != 0x11 {
+		t.Errorf("expected SBUF to read back the receive buffer (0x11), got %#02x", sbuf)
+	}
+
+	// 11 bits x 64 clocks = 704 clocks, just under 59 machine cycles
+	for vm.Cycles() < 58 {
+		feedNops(t, vm, 1)
+	}
+	if vm.SCON()&SCON_TI_MASK != 0 {
+		t.Errorf("expected TI to be clear before the frame is out, %d cycles in", vm.Cycles())
+	}
+
+	feedNops(t, vm, 1)
+	if vm.SCON()&SCON_TI_MASK == 0 {
+		t.Errorf("expected TI to be set once the frame is out, %d cycles in", vm.Cycles())
+	}
+}
+
+func TestSerialBaudRate(t *testing.T) {
+	cases := []struct {
+		Name     string
+		ClockHz  int
+		SCON     byte
+		PCON     byte
+		TMOD     byte
+		TH1      byte
+		Expected float64
+	}{
+		{Name: "mode 0", ClockHz: 12_000_000, SCON: 0x00, Expected: 1_000_000},
+		{Name: "mode 1 9600", ClockHz: 11_059_200, SCON: 0x50, TMOD: 0x20, TH1: 0xFD, Expected: 9600},
+		{Name: "mode 1 19200 SMOD", ClockHz: 11_059_200, SCON: 0x50, PCON: PCON_SMOD_MASK, TMOD: 0x20, TH1: 0xFD, Expected: 19200},
+		{Name: "mode 3 2400", ClockHz: 11_059_200, SCON: 0xD0, TMOD: 0x20, TH1: 0xF4, Expected: 2400},
+		{Name: "mode 2", ClockHz: 12_000_000, SCON: 0x80, Expected: 187_500},
+		{Name: "mode 2 SMOD", ClockHz: 12_000_000, SCON: 0x80, PCON: PCON_SMOD_MASK, Expected: 375_000},
+		{Name: "timer 1 not auto-reload", ClockHz: 12_000_000, SCON: 0x50, TMOD: 0x10, Expected: 0},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.ClockHz = tc.ClockHz
+		vm.WriteMem(SFR_SCON, tc.SCON)
+		vm.WriteMem(SFR_PCON, tc.PCON)
+		vm.WriteMem(SFR_TMOD, tc.TMOD)
+		vm.WriteMem(SFR_TH1, tc.TH1)
+
+		if actual := vm.SerialBaudRate(); actual != tc.Expected {
+			t.Errorf("%s: expected %.0f baud, got %.2f", tc.Name, tc.Expected, actual)
+		}
+	}
+}
+
+func TestSerialTransmitProgram(t *testing.T) {
+	program := []byte{
+		0x75, SFR_TMOD, 0x20, // 0000: MOV TMOD,#20h    timer 1 mode 2
+		0x75, SFR_TH1, 0xFD, //  0003: MOV TH1,#0FDh    9600 baud at 11.0592MHz
+		0x75, SFR_SCON, 0x50, // 0006: MOV SCON,#50h    mode 1, REN
+		0xD2, 0x8E, //           0009: SETB TR1
+		0x90, 0x00, 0x20, //     000B: MOV DPTR,#msg
+		0xE4,       //           000E: CLR A
+		0x93,       //           000F: MOVC A,@A+DPTR
+		0x60, 0x0B, //           0010: JZ done
+		0xF5, SFR_SBUF, //       0012: MOV SBUF,A
+		0x30, 0x99, 0xFD, //     0014: JNB TI,$
+		0xC2, 0x99, //           0017: CLR TI
+		0xA3,       //           0019: INC DPTR
+		0x80, 0xF2, //           001A: SJMP 000E
+		0x80, 0xFE, //           001C: done: SJMP $
+		0x00, 0x00, //           001E: padding
+		'H', 'i', '\n', 0x00, // 0020: msg
+	}
+
+	var out bytes.Buffer
+
+	vm := NewMachine()
+	vm.ClockHz = 11_059_200
+	vm.AttachSerial(nil, &out)
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.Run(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "Hi\n" {
+		t.Errorf("expected the firmware to print %q, got %q", "Hi\n", out.String())
+	}
+
+	// 3 frames of 10 bits at 9600 baud, about 3.1ms
+	if vm.Elapsed().Microseconds() < 3*10*1_000_000/9600 {
+		t.Errorf("expected transmission to take at least 3.1ms, took %s", vm.Elapsed())
+	}
+}
+
+// Stores every received byte from 30h onwards
+func serialEchoProgram(t *testing.T, vm *Machine) {
+	t.Helper()
+
+	program := []byte{
+		0x75, SFR_TMOD, 0x20, // 0000: MOV TMOD,#20h
+		0x75, SFR_TH1, 0xFF, //  0003: MOV TH1,#0FFh
+		0x75, SFR_SCON, 0x50, // 0006: MOV SCON,#50h    mode 1, REN
+		0xD2, 0x8E, //           0009: SETB TR1
+		0x78, 0x30, //           000B: MOV R0,#30h
+		0x30, 0x98, 0xFD, //     000D: JNB RI,$
+		0xC2, 0x98, //           0010: CLR RI
+		0xE5, SFR_SBUF, //       0012: MOV A,SBUF
+		0xF6,       //           0014: MOV @R0,A
+		0x08,       //           0015: INC R0
+		0x80, 0xF5, //           0016: SJMP 000D
+	}
+
+	if err := vm.Load(program); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSerialReceiveProgram(t *testing.T) {
+	vm := NewMachine()
+	serialEchoProgram(t, vm)
+	vm.QueueSerialFrame('o', true)
+	vm.QueueSerialFrame('k', true)
+
+	// 2 frames of 10 bits x 32 overflows, timer 1 overflows every cycle
+	if _, err := vm.Run(2000); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := vm.ReadMem(0x30)
+	second, _ := vm.ReadMem(0x31)
+	if first != 'o' || second != 'k' {
+		t.Errorf("expected %q at 30h, got %q", "ok", []byte{first, second})
+	}
+}
+
+func TestSerialReceiveFromReader(t *testing.T) {
+	vm := NewMachine()
+	serialEchoProgram(t, vm)
+	vm.AttachSerial(strings.NewReader("ok"), nil)
+
+	// the reader is drained by a goroutine, so when the bytes show up in
+	// simulated time is up to the scheduler. Keep running until they do
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := vm.Run(1000); err != nil {
+			t.Fatal(err)
+		}
+
+		if second, _ := vm.ReadMem(0x31); second != 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			first, _ := vm.ReadMem(0x30)
+			t.Fatalf("expected %q from the reader within 5s, got %q", "ok", []byte{first})
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	first, _ := vm.ReadMem(0x30)
+	second, _ := vm.ReadMem(0x31)
+	if first != 'o' || second != 'k' {
+		t.Errorf("expected %q at 30h, got %q", "ok", []byte{first, second})
+	}
+}
+
+func TestSerialMultiprocessor(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SCON, SCON_SM0_MASK|SCON_SM1_MASK|SCON_SM2_MASK|SCON_REN_MASK) // mode 3, SM2
+	vm.WriteMem(SFR_TMOD, 0x20)
+	vm.WriteMem(SFR_TH1, 0xFF)
+	vm.WriteMem(SFR_TL1, 0xFF)
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+
+	vm.QueueSerialFrame(0x55, false) // data, not for us yet
+	vm.QueueSerialFrame(0x01, true)  // address
+
+	// two frames of 11 bits x 32 overflows, timer 1 overflows every cycle
+	feedNops(t, vm, 2*11*32)
+
+	sbuf, _ := vm.ReadMem(SFR_SBUF)
+	if vm.SCON()&SCON_RI_MASK == 0 || sbuf != 0x01 || vm.SCON()&SCON_RB8_MASK == 0 {
+		t.Fatalf("expected only the address frame through, got SBUF=%#02x SCON=%#08b", sbuf, vm.SCON())
+	}
+
+	// addressed: clear SM2 and RI to take the data frames
+	vm.WriteMem(SFR_SCON, vm.SCON() & ^(SCON_SM2_MASK|SCON_RI_MASK))
+	vm.QueueSerialFrame(0xAA, false)
+	feedNops(t, vm, 11*32)
+
+	sbuf, _ = vm.ReadMem(SFR_SBUF)
+	if vm.SCON()&SCON_RI_MASK == 0 || sbuf != 0xAA || vm.SCON()&SCON_RB8_MASK != 0 {
+		t.Errorf("expected data frame with RB8 clear, got SBUF=%#02x SCON=%#08b", sbuf, vm.SCON())
+	}
+}
+
+func TestSerialOverrun(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SCON, SCON_SM1_MASK|SCON_REN_MASK) // mode 1
+	vm.WriteMem(SFR_TMOD, 0x20)
+	vm.WriteMem(SFR_TH1, 0xFF)
+	vm.WriteMem(SFR_TL1, 0xFF)
+	vm.WriteMem(SFR_PCON, PCON_SMOD_MASK)
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+
+	vm.QueueSerialFrame('a', true)
+	vm.QueueSerialFrame('b', true)
+	feedNops(t, vm, 2*10*16)
+
+	sbuf, _ := vm.ReadMem(SFR_SBUF)
+	if sbuf != 'a' {
+		t.Errorf("expected the second frame to be lost while RI is set, SBUF is %q", sbuf)
+	}
+}
+
+func TestSerialMode0(t *testing.T) {
+	var out bytes.Buffer
+
+	vm := NewMachine()
+	vm.AttachSerial(nil, &out)
+	vm.WriteMem(SFR_SCON, SCON_RI_MASK) // mode 0, RI still set
+
+	vm.WriteMem(SFR_SBUF, 0x3C)
+	feedNops(t, vm, 8)
+
+	if vm.SCON()&SCON_TI_MASK == 0 || !bytes.Equal(out.Bytes(), []byte{0x3C}) {
+		t.Errorf("expected 8 bits shifted out in 8 cycles, got TI=%t out=%v", vm.SCON()&SCON_TI_MASK != 0, out.Bytes())
+	}
+
+	// reception needs REN and RI clear
+	vm.QueueSerialFrame(0xC3, false)
+	vm.WriteMem(SFR_SCON, SCON_REN_MASK|SCON_RI_MASK)
+	feedNops(t, vm, 16)
+	if sbuf, _ := vm.ReadMem(SFR_SBUF); sbuf == 0xC3 {
+		t.Errorf("expected no reception while RI is set")
+	}
+
+	vm.WriteMem(SFR_SCON, SCON_REN_MASK)
+	feedNops(t, vm, 8)
+	if sbuf, _ := vm.ReadMem(SFR_SBUF); sbuf != 0xC3 || vm.SCON()&SCON_RI_MASK == 0 {
+		t.Errorf("expected 0xc3 received with RI set, got %#02x SCON=%#08b", sbuf, vm.SCON())
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+// Typed accessors for the SFRs. Machine.Data is the only place SFR
+// state is stored, so these always agree with ReadMem/WriteMem
+
+func (m *Machine) ACC() byte {
+	return m.Data[SFR_ACC]
+}
+
+func (m *Machine) B() byte {
+	return m.Data[SFR_B]
+}
+
+func (m *Machine) PSW() byte {
+	return m.Data[SFR_PSW]
+}
+
+// Data pointer, DPH:DPL
+func (m *Machine) DPTR() uint16 {
+	return uint16(m.Data[SFR_DPH])<<8 | uint16(m.Data[SFR_DPL])
+}
+
+func (m *Machine) IE() byte {
+	return m.Data[SFR_IE]
+}
+
+func (m *Machine) IP() byte {
+	return m.Data[SFR_IP]
+}
+
+func (m *Machine) P0() byte {
+	return m.Data[SFR_P0]
+}
+
+func (m *Machine) P1() byte {
+	return m.Data[SFR_P1]
+}
+
+func (m *Machine) P2() byte {
+	return m.Data[SFR_P2]
+}
+
+func (m *Machine) P3() byte {
+	return m.Data[SFR_P3]
+}
+
+func (m *Machine) PCON() byte {
+	return m.Data[SFR_PCON]
+}
+
+func (m *Machine) SCON() byte {
+	return m.Data[SFR_SCON]
+}
+
+func (m *Machine) SBUF() byte {
+	return m.Data[SFR_SBUF]
+}
+
+func (m *Machine) TMOD() byte {
+	return m.Data[SFR_TMOD]
+}
+
+func (m *Machine) TCON() byte {
+	return m.Data[SFR_TCON]
+}
+
+func (m *Machine) TL0() byte {
+	return m.Data[SFR_TL0]
+}
+
+func (m *Machine) TH0() byte {
+	return m.Data[SFR_TH0]
+}
+
+func (m *Machine) TL1() byte {
+	return m.Data[SFR_TL1]
+}
+
+func (m *Machine) TH1() byte {
+	return m.Data[SFR_TH1]
+}
+
+func (m *Machine) T2CON() byte {
+	return m.Data[SFR_T2CON]
+}
+
+func (m *Machine) T2MOD() byte {
+	return m.Data[SFR_T2MOD]
+}
+
+func (m *Machine) RCAP2L() byte {
+	return m.Data[SFR_RCAP2L]
+}
+
+func (m *Machine) RCAP2H() byte {
+	return m.Data[SFR_RCAP2H]
+}
+
+func (m *Machine) TL2() byte {
+	return m.Data[SFR_TL2]
+}
+
+func (m *Machine) TH2() byte {
+	return m.Data[SFR_TH2]
+}
+
+// Copies the current SFR values out of memory, mostly for logging
+func (m *Machine) Registers() Register {
+	return Register{
+		ACC:  m.Data[SFR_ACC],
+		B:    m.Data[SFR_B],
+		DPH:  m.Data[SFR_DPH],
+		DPL:  m.Data[SFR_DPL],
+		IE:   m.Data[SFR_IE],
+		IP:   m.Data[SFR_IP],
+		P0:   m.Data[SFR_P0],
+		P1:   m.Data[SFR_P1],
+		P2:   m.Data[SFR_P2],
+		P3:   m.Data[SFR_P3],
+		PCON: m.Data[SFR_PCON],
+		PSW:  m.Data[SFR_PSW],
+		SCON: m.Data[SFR_SCON],
+		SBUF: m.Data[SFR_SBUF],
+		SP:   m.Data[SFR_SP],
+		TMOD: m.Data[SFR_TMOD],
+		TCON: m.Data[SFR_TCON],
+		TL0:  m.Data[SFR_TL0],
+		TH0:  m.Data[SFR_TH0],
+		TL1:  m.Data[SFR_TL1],
+		TH1:  m.Data[SFR_TH1],
+
+		T2CON:  m.Data[SFR_T2CON],
+		T2MOD:  m.Data[SFR_T2MOD],
+		RCAP2L: m.Data[SFR_RCAP2L],
+		RCAP2H: m.Data[SFR_RCAP2H],
+		TL2:    m.Data[SFR_TL2],
+		TH2:    m.Data[SFR_TH2],
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Stack faults the machine can be asked to report, see Machine.StackGuard.
+// A real 8051 never notices any of these, it just corrupts memory
+type StackGuard byte
+
+const (
+	STACK_GUARD_OVERFLOW  StackGuard = (1 << 0) // push past the top of internal RAM
+	STACK_GUARD_UNDERFLOW StackGuard = (1 << 1) // pop below the SP the program set up
+	STACK_GUARD_BANKS     StackGuard = (1 << 2) // push into the selected register bank
+	STACK_GUARD_BIT_AREA  StackGuard = (1 << 3) // push into the bit-addressable area 0x20-0x2F
+)
+
+const STACK_GUARD_ALL StackGuard = STACK_GUARD_OVERFLOW | STACK_GUARD_UNDERFLOW | STACK_GUARD_BANKS | STACK_GUARD_BIT_AREA
+
+func (g StackGuard) String() string {
+	switch g {
+	case STACK_GUARD_OVERFLOW:
+		return "overflow"
+	case STACK_GUARD_UNDERFLOW:
+		return "underflow"
+	case STACK_GUARD_BANKS:
+		return "register bank"
+	case STACK_GUARD_BIT_AREA:
+		return "bit area"
+	}
+
+	return fmt.Sprintf("StackGuard(%#02x)", byte(g))
+}
+
+// Returned (wrapped) by instructions that trip an enabled stack guard,
+// use errors.As to get at it
+type StackFault struct {
+	Kind StackGuard
+	SP   uint8  // SP before the offending push/pop
+	Addr uint8  // stack address the push/pop would have used
+	PC   uint16 // address of the instruction following the push/pop
+}
+
+func (f *StackFault) Error() string {
+	return fmt.Sprintf("stack %s: SP %#02x, address %#02x", f.Kind, f.SP, f.Addr)
+}
+
+func (m *Machine) SP() byte {
+	return m.Data[SFR_SP]
+}
+
+// Pushes a byte onto the internal stack through the SP SFR (0x81).
+// SP is incremented before the write.
+func (m *Machine) stackPush(value byte) error {
+	sp := m.Data[SFR_SP]
+	addr := sp + 1
+
+	if m.StackGuard&STACK_GUARD_OVERFLOW != 0 && int(sp)+1 >= m.IRAMSize() {
+		return &StackFault{Kind: STACK_GUARD_OVERFLOW, SP: sp, Addr: addr, PC: m.PC}
+	}
+
+	bank := m.bankOffset()
+	if m.StackGuard&STACK_GUARD_BANKS != 0 && addr >= bank && addr < bank+BANK_SIZE {
+		return &StackFault{Kind: STACK_GUARD_BANKS, SP: sp, Addr: addr, PC: m.PC}
+	}
+
+	if m.StackGuard&STACK_GUARD_BIT_AREA != 0 && addr >= LOC_BIT_AREA && addr < LOC_BIT_AREA+16 {
+		return &StackFault{Kind: STACK_GUARD_BIT_AREA, SP: sp, Addr: addr, PC: m.PC}
+	}
+
+	if err := m.WriteIndirect(addr, value); err != nil {
+		return err
+	}
+
+	// not through WriteMem, the bottom of the stack stays where the program put it
+	m.Data[SFR_SP] = addr
+	return nil
+}
+
+// Pops a byte off the internal stack through the SP SFR (0x81).
+// SP is decremented after the read.
+func (m *Machine) stackPop() (byte, error) {
+	sp := m.Data[SFR_SP]
+
+	if m.StackGuard&STACK_GUARD_UNDERFLOW != 0 && sp <= m.stackBase {
+		return 0, &StackFault{Kind: STACK_GUARD_UNDERFLOW, SP: sp, Addr: sp, PC: m.PC}
+	}
+
+	val, err := m.ReadIndirect(sp)
+	if err != nil {
+		return 0, err
+	}
+
+	m.Data[SFR_SP] = sp - 1
+	return val, nil
+}
+
+// Saves PC on the stack, low byte first
+func (m *Machine) pushPC() error {
+	sp := m.Data[SFR_SP]
+
+	if err := m.stackPush(byte(m.PC)); err != nil {
+		return err
+	}
+
+	if err := m.stackPush(byte(m.PC >> 8)); err != nil {
+		m.Data[SFR_SP] = sp
+		return err
+	}
+
+	return nil
+}
+
+// Restores PC from the stack, high byte first
+func (m *Machine) popPC() error {
+	sp := m.Data[SFR_SP]
+
+	hi, err := m.stackPop()
+	if err != nil {
+		return err
+	}
+
+	lo, err := m.stackPop()
+	if err != nil {
+		m.Data[SFR_SP] = sp
+		return err
+	}
+
+	m.PC = uint16(hi)<<8 | uint16(lo)
+	return nil
+}
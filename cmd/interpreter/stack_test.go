@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPushPopHonourSP(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(0x30, 0xA5)
+
+	if err := vm.Feed([]byte{0x75, SFR_SP, 0x60}); err != nil { // MOV SP,#60h
+		t.Fatal(err)
+	}
+
+	if err := vm.Feed([]byte{0xC0, 0x30}); err != nil { // PUSH 30h
+		t.Fatal(err)
+	}
+
+	if vm.SP() != 0x61 {
+		t.Errorf("expected SP to be 0x61 after PUSH, got %#02x", vm.SP())
+	}
+
+	pushed, _ := vm.ReadIndirect(0x61)
+	if pushed != 0xA5 {
+		t.Errorf("expected 0xa5 at 0x61, got %#02x", pushed)
+	}
+
+	if err := vm.Feed([]byte{0xD0, 0x31}); err != nil { // POP 31h
+		t.Fatal(err)
+	}
+
+	if vm.SP() != 0x60 {
+		t.Errorf("expected SP to be back at 0x60 after POP, got %#02x", vm.SP())
+	}
+
+	popped, _ := vm.ReadMem(0x31)
+	if popped != 0xA5 {
+		t.Errorf("expected POP to write 0xa5 to 0x31, got %#02x", popped)
+	}
+}
+
+func TestStackGuard(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Guard StackGuard
+		SP    uint8
+		Bank  byte
+		Op    []byte
+		Fault StackGuard
+	}{
+		{Name: "overflow", Guard: STACK_GUARD_OVERFLOW, SP: 0xFF, Op: []byte{0xC0, 0x30}, Fault: STACK_GUARD_OVERFLOW},
+		{Name: "underflow", Guard: STACK_GUARD_UNDERFLOW, SP: 0x60, Op: []byte{0xD0, 0x30}, Fault: STACK_GUARD_UNDERFLOW},
+		{Name: "RET underflow", Guard: STACK_GUARD_ALL, SP: 0x60, Op: []byte{0x22}, Fault: STACK_GUARD_UNDERFLOW},
+		{Name: "active bank", Guard: STACK_GUARD_BANKS, SP: 0x07, Bank: 1, Op: []byte{0xC0, 0x30}, Fault: STACK_GUARD_BANKS},
+		{Name: "inactive bank", Guard: STACK_GUARD_BANKS, SP: 0x07, Bank: 0, Op: []byte{0xC0, 0x30}},
+		{Name: "bit area", Guard: STACK_GUARD_BIT_AREA, SP: 0x1F, Op: []byte{0xC0, 0x30}, Fault: STACK_GUARD_BIT_AREA},
+		{Name: "LCALL into bit area", Guard: STACK_GUARD_ALL, SP: 0x1E, Op: []byte{0x12, 0x01, 0x00}, Fault: STACK_GUARD_BIT_AREA},
+		{Name: "disabled", Guard: 0, SP: 0xFF, Op: []byte{0xC0, 0x30}},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.StackGuard = tc.Guard
+		vm.SetBankNo(tc.Bank)
+		vm.WriteMem(SFR_SP, tc.SP)
+
+		err := vm.Feed(tc.Op)
+
+		if tc.Fault == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tc.Name, err)
+			}
+			continue
+		}
+
+		var fault *StackFault
+		if !errors.As(err, &fault) {
+			t.Errorf("%s: expected a StackFault, got %v", tc.Name, err)
+			continue
+		}
+
+		if fault.Kind != tc.Fault {
+			t.Errorf("%s: expected %s fault, got %s", tc.Name, tc.Fault, fault.Kind)
+		}
+
+		if vm.SP() != tc.SP {
+			t.Errorf("%s: expected SP to stay at %#02x, got %#02x", tc.Name, tc.SP, vm.SP())
+		}
+
+		if vm.PC != 0 {
+			t.Errorf("%s: expected PC to stay at 0, got %#04x", tc.Name, vm.PC)
+		}
+	}
+}
+
+func TestStackGuardBaseFollowsProgram(t *testing.T) {
+	vm := NewMachine()
+	vm.StackGuard = STACK_GUARD_UNDERFLOW
+	vm.WriteMem(SFR_SP, 0x40)
+
+	if err := vm.Feed([]byte{0xC0, 0x30}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vm.Feed([]byte{0xD0, 0x30}); err != nil {
+		t.Errorf("popping what was pushed must not underflow: %s", err)
+	}
+
+	if err := vm.Feed([]byte{0xD0, 0x30}); err == nil {
+		t.Errorf("expected underflow popping past the base, nil given")
+	}
+
+	// moving SP sets a new bottom of the stack
+	if err := vm.Feed([]byte{0x75, SFR_SP, 0x50}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vm.Feed([]byte{0xD0, 0x30}); err == nil {
+		t.Errorf("expected underflow at the new base, nil given")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+// TCON bits
+const TCON_TF1_MASK byte = (1 << 7)
+const TCON_TR1_MASK byte = (1 << 6)
+const TCON_TF0_MASK byte = (1 << 5)
+const TCON_TR0_MASK byte = (1 << 4)
+const TCON_IE1_MASK byte = (1 << 3)
+const TCON_IT1_MASK byte = (1 << 2)
+const TCON_IE0_MASK byte = (1 << 1)
+const TCON_IT0_MASK byte = (1 << 0)
+
+// TMOD bits, per timer nibble (timer 1 upper, timer 0 lower)
+const TMOD_GATE_MASK byte = (1 << 3)
+const TMOD_CT_MASK byte = (1 << 2)
+const TMOD_MODE_MASK byte = 0b11
+
+const TIMER_MODE_13BIT byte = 0
+const TIMER_MODE_16BIT byte = 1
+const TIMER_MODE_AUTO_RELOAD byte = 2
+const TIMER_MODE_SPLIT byte = 3
+
+// Drives the T0 (P3.4) counter input from outside the chip
+func (m *Machine) SetT0(high bool) {
+	m.setP3Input(P3_T0_MASK, high)
+}
+
+// Drives the T1 (P3.5) counter input from outside the chip
+func (m *Machine) SetT1(high bool) {
+	m.setP3Input(P3_T1_MASK, high)
+}
+
+// Timers count once every 12 oscillator periods, once per machine cycle
+// on the classic 8051. CKCON on the DS89C4x0 can select 4 instead
+const TIMER_CLOCKS int = 12
+const TIMER_CLOCKS_FAST int = 4
+
+// CKCON timer clock selects, see Device.TimerClockSelect
+const CKCON_T2M_MASK byte = (1 << 5)
+const CKCON_T1M_MASK byte = (1 << 4)
+const CKCON_T0M_MASK byte = (1 << 3)
+
+// Oscillator periods per count of a timer in timer mode
+func (m *Machine) timerDivider(ckconMask byte) int {
+	if m.device.TimerClockSelect && m.Data[SFR_CKCON]&ckconMask != 0 {
+		return TIMER_CLOCKS_FAST
+	}
+
+	return TIMER_CLOCKS
+}
+
+// Feeds one machine cycle worth of oscillator periods into a timer's
+// prescaler, so the timer rate does not depend on ClocksPerCycle
+//
+// @return int - how many times the timer counts this cycle in timer mode
+func (m *Machine) timerTicks(clocks *int, divider int) int {
+	*clocks += m.ClocksPerCycle
+	ticks := *clocks / divider
+	*clocks %= divider
+
+	return ticks
+}
+
+// Advances timers 0 and 1 by one machine cycle
+//
+// @return int - how often timer 1 overflowed, for the serial baud rate
+func (m *Machine) tickTimers() int {
+	// counters count 1-to-0 transitions, sampled once per cycle
+	t0 := m.p3Pin(P3_T0_MASK)
+	t1 := m.p3Pin(P3_T1_MASK)
+	t0Fell := m.t0Level && !t0
+	t1Fell := m.t1Level && !t1
+	m.t0Level = t0
+	m.t1Level = t1
+
+	m.tickTimer0(t0Fell, m.timerTicks(&m.t0Clocks, m.timerDivider(CKCON_T0M_MASK)))
+	return m.tickTimer1(t1Fell, m.timerTicks(&m.t1Clocks, m.timerDivider(CKCON_T1M_MASK)))
+}
+
+func (m *Machine) tickTimer0(countPulse bool, ticks int) {
+	ctrl := m.Data[SFR_TMOD] & 0x0F
+	tcon := m.Data[SFR_TCON]
+
+	counts := m.timerCounts(ctrl, tcon&TCON_TR0_MASK != 0, m.p3Pin(P3_INT0_MASK), countPulse, ticks)
+	for i := 0; i < counts; i++ {
+		// in mode 3 TL0 is a plain 8-bit timer/counter
+		if countTimer(m.Data, SFR_TL0, SFR_TH0, ctrl&TMOD_MODE_MASK) {
+			m.Data[SFR_TCON] |= TCON_TF0_MASK
+		}
+	}
+
+	// in mode 3 TH0 is a plain 8-bit timer borrowing TR1 and TF1
+	if ctrl&TMOD_MODE_MASK == TIMER_MODE_SPLIT && tcon&TCON_TR1_MASK != 0 {
+		for i := 0; i < ticks; i++ {
+			m.Data[SFR_TH0]++
+			if m.Data[SFR_TH0] == 0 {
+				m.Data[SFR_TCON] |= TCON_TF1_MASK
+			}
+		}
+	}
+}
+
+func (m *Machine) tickTimer1(countPulse bool, ticks int) int {
+	ctrl := m.Data[SFR_TMOD] >> 4
+	tcon := m.Data[SFR_TCON]
+	mode := ctrl & TMOD_MODE_MASK
+
+	// mode 3 just stops timer 1
+	if mode == TIMER_MODE_SPLIT {
+		return 0
+	}
+
+	// while timer 0 is split, TR1 and TF1 belong to TH0 and timer 1
+	// free-runs (it can still clock the serial port)
+	split := m.Data[SFR_TMOD]&TMOD_MODE_MASK == TIMER_MODE_SPLIT
+	run := tcon&TCON_TR1_MASK != 0 || split
+
+	overflows := 0
+	counts := m.timerCounts(ctrl, run, m.p3Pin(P3_INT1_MASK), countPulse, ticks)
+	for i := 0; i < counts; i++ {
+		if !countTimer(m.Data, SFR_TL1, SFR_TH1, mode) {
+			continue
+		}
+
+		overflows++
+		if !split {
+			m.Data[SFR_TCON] |= TCON_TF1_MASK
+		}
+	}
+
+	return overflows
+}
+
+// How many counts a timer takes this cycle: it has to be running (TRx,
+// and INTx high when GATE is set), and in counter mode see a pin pulse.
+// In timer mode it takes the prescaler's ticks
+func (m *Machine) timerCounts(ctrl byte, run bool, intPin bool, countPulse bool, ticks int) int {
+	if !run {
+		return 0
+	}
+
+	if ctrl&TMOD_GATE_MASK != 0 && !intPin {
+		return 0
+	}
+
+	if ctrl&TMOD_CT_MASK != 0 {
+		if countPulse {
+			return 1
+		}
+		return 0
+	}
+
+	return ticks
+}
+
+// Increments the TLx/THx pair in the given mode, returning true on overflow
+func countTimer(data []byte, tl uint8, th uint8, mode byte) bool {
+	switch mode {
+	case TIMER_MODE_13BIT:
+		// TL counts as a 5-bit prescaler, its upper 3 bits are left alone
+		low := (data[tl] + 1) & 0x1F
+		data[tl] = data[tl]&0xE0 | low
+		if low != 0 {
+			return false
+		}
+
+		data[th]++
+		return data[th] == 0
+	case TIMER_MODE_16BIT:
+		data[tl]++
+		if data[tl] != 0 {
+			return false
+		}
+
+		data[th]++
+		return data[th] == 0
+	case TIMER_MODE_AUTO_RELOAD:
+		data[tl]++
+		if data[tl] != 0 {
+			return false
+		}
+
+		data[tl] = data[th]
+		return true
+	}
+
+	// TIMER_MODE_SPLIT: TL on its own is a plain 8-bit timer
+	data[tl]++
+	return data[tl] == 0
+}
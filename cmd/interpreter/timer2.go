@@ -0,0 +1,183 @@
+package main
+
+// T2CON bits
+const T2CON_TF2_MASK byte = (1 << 7)
+const T2CON_EXF2_MASK byte = (1 << 6)
+const T2CON_RCLK_MASK byte = (1 << 5)
+const T2CON_TCLK_MASK byte = (1 << 4)
+const T2CON_EXEN2_MASK byte = (1 << 3)
+const T2CON_TR2_MASK byte = (1 << 2)
+const T2CON_CT2_MASK byte = (1 << 1)
+const T2CON_CPRL2_MASK byte = (1 << 0)
+
+// Oscillator periods per count as a baud rate generator
+const TIMER2_BAUD_CLOCKS int = 2
+
+// T2MOD bits
+const T2MOD_DCEN_MASK byte = (1 << 0)
+
+// Port 1 alternate functions on 8052-class parts
+const P1_T2_MASK byte = (1 << 0)
+const P1_T2EX_MASK byte = (1 << 1)
+
+// Drives the T2 (P1.0) counter input from outside the chip
+func (m *Machine) SetT2(high bool) {
+	m.drivePins(1, P1_T2_MASK, high)
+}
+
+// Drives the T2EX (P1.1) capture/reload trigger from outside the chip
+func (m *Machine) SetT2EX(high bool) {
+	m.drivePins(1, P1_T2EX_MASK, high)
+}
+
+func (m *Machine) timer2() uint16 {
+	return uint16(m.Data[SFR_TH2])<<8 | uint16(m.Data[SFR_TL2])
+}
+
+func (m *Machine) setTimer2(value uint16) {
+	m.Data[SFR_TH2] = byte(value >> 8)
+	m.Data[SFR_TL2] = byte(value)
+}
+
+func (m *Machine) rcap2() uint16 {
+	return uint16(m.Data[SFR_RCAP2H])<<8 | uint16(m.Data[SFR_RCAP2L])
+}
+
+// Whether timer 2 clocks the serial port rather than counting on its own
+func timer2Baud(t2con byte) bool {
+	return t2con&(T2CON_RCLK_MASK|T2CON_TCLK_MASK) != 0
+}
+
+// DCEN only has an effect in auto-reload mode
+func timer2UpDown(t2con byte, t2mod byte) bool {
+	return t2mod&T2MOD_DCEN_MASK != 0 && !timer2Baud(t2con) && t2con&T2CON_CPRL2_MASK == 0
+}
+
+// Advances timer 2 by one machine cycle
+//
+// @return int - how often it overflowed as a baud rate generator
+func (m *Machine) tickTimer2() int {
+	// T2 and T2EX are sampled once per cycle like T0/T1
+	t2 := m.p1Pin(P1_T2_MASK)
+	t2ex := m.p1Pin(P1_T2EX_MASK)
+	t2Fell := m.t2Level && !t2
+	t2exFell := m.t2exLevel && !t2ex
+	m.t2Level = t2
+	m.t2exLevel = t2ex
+
+	t2con := m.Data[SFR_T2CON]
+	baud := timer2Baud(t2con)
+	upDown := timer2UpDown(t2con, m.Data[SFR_T2MOD])
+
+	// with DCEN set T2EX picks the count direction instead
+	if t2exFell && t2con&T2CON_EXEN2_MASK != 0 && !upDown {
+		m.timer2External(t2con, baud)
+	}
+
+	if t2con&T2CON_TR2_MASK == 0 {
+		return 0
+	}
+
+	// as a baud rate generator the timer runs at fosc/2
+	divider := m.timerDivider(CKCON_T2M_MASK)
+	if baud {
+		divider = TIMER2_BAUD_CLOCKS
+	}
+
+	counts := m.timerTicks(&m.t2Clocks, divider)
+	if t2con&T2CON_CT2_MASK != 0 {
+		if !t2Fell {
+			return 0
+		}
+		counts = 1
+	}
+
+	overflows := 0
+	for i := 0; i < counts; i++ {
+		switch {
+		case baud:
+			if m.countTimer2Up() {
+				overflows++
+			}
+		case t2con&T2CON_CPRL2_MASK != 0:
+			// capture mode is a plain 16-bit timer
+			m.setTimer2(m.timer2() + 1)
+			if m.timer2() == 0 {
+				m.Data[SFR_T2CON] |= T2CON_TF2_MASK
+			}
+		case upDown && !t2ex:
+			m.countTimer2Down()
+		default:
+			if m.countTimer2Up() {
+				m.Data[SFR_T2CON] |= T2CON_TF2_MASK
+
+				// counting up and down EXF2 is a 17th bit
+				if upDown {
+					m.Data[SFR_T2CON] ^= T2CON_EXF2_MASK
+				}
+			}
+		}
+	}
+
+	return overflows
+}
+
+// Counts up, reloading from RCAP2H:RCAP2L on overflow
+//
+// @return bool - whether it overflowed
+func (m *Machine) countTimer2Up() bool {
+	next := m.timer2() + 1
+	if next != 0 {
+		m.setTimer2(next)
+		return false
+	}
+
+	m.setTimer2(m.rcap2())
+	return true
+}
+
+// Counts down to RCAP2H:RCAP2L, where it underflows to FFFFh
+func (m *Machine) countTimer2Down() {
+	if m.timer2() != m.rcap2() {
+		m.setTimer2(m.timer2() - 1)
+		return
+	}
+
+	m.setTimer2(0xFFFF)
+	m.Data[SFR_T2CON] |= T2CON_TF2_MASK
+	m.Data[SFR_T2CON] ^= T2CON_EXF2_MASK
+}
+
+// A falling edge on T2EX with EXEN2 set captures TH2:TL2 in capture
+// mode or forces a reload in auto-reload mode. Either way it sets EXF2,
+// which is all it does while timer 2 is a baud rate generator
+func (m *Machine) timer2External(t2con byte, baud bool) {
+	m.Data[SFR_T2CON] |= T2CON_EXF2_MASK
+
+	if baud {
+		return
+	}
+
+	if t2con&T2CON_CPRL2_MASK != 0 {
+		m.Data[SFR_RCAP2H] = m.Data[SFR_TH2]
+		m.Data[SFR_RCAP2L] = m.Data[SFR_TL2]
+		return
+	}
+
+	m.setTimer2(m.rcap2())
+}
+
+// Timer 2 requests an interrupt with TF2 or EXF2, except that EXF2 is
+// just a count bit while counting up and down
+func (m *Machine) timer2Pending() bool {
+	t2con := m.Data[SFR_T2CON]
+	if t2con&T2CON_TF2_MASK != 0 {
+		return true
+	}
+
+	if t2con&T2CON_EXF2_MASK == 0 {
+		return false
+	}
+
+	return !timer2UpDown(t2con, m.Data[SFR_T2MOD])
+}
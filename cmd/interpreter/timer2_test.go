@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTimer2Modes(t *testing.T) {
+	cases := []struct {
+		Name     string
+		T2CON    byte
+		T2MOD    byte
+		Timer    uint16
+		RCAP2    uint16
+		T2EX     bool // pull T2EX low before running
+		Cycles   int
+		Expected uint16
+		RCAP2Exp uint16
+		TF2      bool
+		EXF2     bool
+	}{
+		{Name: "auto-reload count", T2CON: T2CON_TR2_MASK, Timer: 0x1000, RCAP2: 0x8000, Cycles: 3, Expected: 0x1003, RCAP2Exp: 0x8000},
+		{Name: "auto-reload overflow", T2CON: T2CON_TR2_MASK, Timer: 0xFFFE, RCAP2: 0xFFF0, Cycles: 2, Expected: 0xFFF0, RCAP2Exp: 0xFFF0, TF2: true},
+		{Name: "auto-reload ignores T2EX", T2CON: T2CON_TR2_MASK, Timer: 0x1000, RCAP2: 0x8000, T2EX: true, Cycles: 1, Expected: 0x1001, RCAP2Exp: 0x8000},
+		{Name: "auto-reload on T2EX", T2CON: T2CON_TR2_MASK | T2CON_EXEN2_MASK, Timer: 0x1000, RCAP2: 0x8000, T2EX: true, Cycles: 1, Expected: 0x8001, RCAP2Exp: 0x8000, EXF2: true},
+		{Name: "capture overflow", T2CON: T2CON_TR2_MASK | T2CON_CPRL2_MASK, Timer: 0xFFFF, RCAP2: 0x8000, Cycles: 1, Expected: 0x0000, RCAP2Exp: 0x8000, TF2: true},
+		{Name: "capture on T2EX", T2CON: T2CON_TR2_MASK | T2CON_CPRL2_MASK | T2CON_EXEN2_MASK, Timer: 0x1234, RCAP2: 0x8000, T2EX: true, Cycles: 1, Expected: 0x1235, RCAP2Exp: 0x1234, EXF2: true},
+		{Name: "stopped capture on T2EX", T2CON: T2CON_CPRL2_MASK | T2CON_EXEN2_MASK, Timer: 0x1234, RCAP2: 0x8000, T2EX: true, Cycles: 1, Expected: 0x1234, RCAP2Exp: 0x1234, EXF2: true},
+		{Name: "up/down counting up", T2CON: T2CON_TR2_MASK, T2MOD: T2MOD_DCEN_MASK, Timer: 0xFFFF, RCAP2: 0xFF00, Cycles: 1, Expected: 0xFF00, RCAP2Exp: 0xFF00, TF2: true, EXF2: true},
+		{Name: "up/down counting down", T2CON: T2CON_TR2_MASK, T2MOD: T2MOD_DCEN_MASK, Timer: 0xFF02, RCAP2: 0xFF00, T2EX: true, Cycles: 2, Expected: 0xFF00, RCAP2Exp: 0xFF00},
+		{Name: "up/down underflow", T2CON: T2CON_TR2_MASK, T2MOD: T2MOD_DCEN_MASK, Timer: 0xFF01, RCAP2: 0xFF00, T2EX: true, Cycles: 2, Expected: 0xFFFF, RCAP2Exp: 0xFF00, TF2: true, EXF2: true},
+		{Name: "up/down T2EX is no trigger", T2CON: T2CON_TR2_MASK | T2CON_EXEN2_MASK, T2MOD: T2MOD_DCEN_MASK, Timer: 0x1000, RCAP2: 0x0800, T2EX: true, Cycles: 1, Expected: 0x0FFF, RCAP2Exp: 0x0800},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_T2MOD, tc.T2MOD)
+		vm.WriteMem(SFR_TH2, byte(tc.Timer>>8))
+		vm.WriteMem(SFR_TL2, byte(tc.Timer))
+		vm.WriteMem(SFR_RCAP2H, byte(tc.RCAP2>>8))
+		vm.WriteMem(SFR_RCAP2L, byte(tc.RCAP2))
+		vm.WriteMem(SFR_T2CON, tc.T2CON)
+		if tc.T2EX {
+			vm.SetT2EX(false)
+		}
+
+		feedNops(t, vm, tc.Cycles)
+
+		timer := uint16(vm.TH2())<<8 | uint16(vm.TL2())
+		if timer != tc.Expected {
+			t.Errorf("%s: expected TH2:TL2 to be %04X, got %04X", tc.Name, tc.Expected, timer)
+		}
+
+		rcap2 := uint16(vm.RCAP2H())<<8 | uint16(vm.RCAP2L())
+		if rcap2 != tc.RCAP2Exp {
+			t.Errorf("%s: expected RCAP2H:RCAP2L to be %04X, got %04X", tc.Name, tc.RCAP2Exp, rcap2)
+		}
+
+		if tf2 := vm.T2CON()&T2CON_TF2_MASK != 0; tf2 != tc.TF2 {
+			t.Errorf("%s: expected TF2 to be %t, got %t", tc.Name, tc.TF2, tf2)
+		}
+
+		if exf2 := vm.T2CON()&T2CON_EXF2_MASK != 0; exf2 != tc.EXF2 {
+			t.Errorf("%s: expected EXF2 to be %t, got %t", tc.Name, tc.EXF2, exf2)
+		}
+	}
+}
+
+func TestTimer2Counter(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_T2CON, T2CON_TR2_MASK|T2CON_CT2_MASK)
+
+	for i := 0; i < 3; i++ {
+		vm.SetT2(false)
+		feedNops(t, vm, 1)
+		vm.SetT2(true)
+		feedNops(t, vm, 1)
+	}
+
+	if vm.TL2() != 3 || vm.TH2() != 0 {
+		t.Errorf("expected 3 counts on T2, got %02X%02X", vm.TH2(), vm.TL2())
+	}
+}
+
+func TestTimer2Interrupt(t *testing.T) {
+	cases := []struct {
+		Name  string
+		T2CON byte
+		T2MOD byte
+		Taken bool
+	}{
+		{Name: "TF2", T2CON: T2CON_TF2_MASK, Taken: true},
+		{Name: "EXF2", T2CON: T2CON_EXF2_MASK, Taken: true},
+		{Name: "EXF2 counting up/down", T2CON: T2CON_EXF2_MASK, T2MOD: T2MOD_DCEN_MASK, Taken: false},
+	}
+
+	for _, tc := range cases {
+		vm := nopProgram(t)
+		vm.WriteMem(SFR_T2MOD, tc.T2MOD)
+		vm.WriteMem(SFR_T2CON, tc.T2CON)
+		vm.WriteMem(SFR_IE, IE_EA_MASK|IE_ET2_MASK)
+
+		if err := vm.Step(); err != nil {
+			t.Fatal(err)
+		}
+
+		if taken := vm.PC == 0x002B; taken != tc.Taken {
+			t.Errorf("%s: expected interrupt taken to be %t, PC is %#04x", tc.Name, tc.Taken, vm.PC)
+		}
+
+		// the flags are for the service routine to clear
+		if vm.T2CON() != tc.T2CON {
+			t.Errorf("%s: expected T2CON to stay %#02x, got %#02x", tc.Name, tc.T2CON, vm.T2CON())
+		}
+	}
+}
+
+func TestTimer2BaudRate(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SCON, 0x40) // mode 1
+	vm.WriteMem(SFR_RCAP2H, 0xFF)
+	vm.WriteMem(SFR_RCAP2L, 0xFA)
+	vm.WriteMem(SFR_TH2, 0xFF)
+	vm.WriteMem(SFR_TL2, 0xFA)
+	vm.WriteMem(SFR_T2CON, T2CON_TCLK_MASK|T2CON_TR2_MASK)
+
+	// 12MHz / 32 / 6
+	if rate := vm.SerialBaudRate(); rate != 62500 {
+		t.Errorf("expected 62500 baud, got %f", rate)
+	}
+
+	// at fosc/2 timer 2 takes 6 counts per machine cycle, so it overflows
+	// once a cycle and a 10-bit frame of 16 overflows per bit takes 160
+	vm.WriteMem(SFR_SBUF, 0x55)
+	feedNops(t, vm, 159)
+
+	if vm.SCON()&SCON_TI_MASK != 0 {
+		t.Fatalf("expected the frame to still be shifting out after 159 cycles")
+	}
+
+	feedNops(t, vm, 1)
+
+	if vm.SCON()&SCON_TI_MASK == 0 {
+		t.Errorf("expected TI after 160 cycles")
+	}
+
+	// overflows only clock the serial port
+	if vm.T2CON()&T2CON_TF2_MASK != 0 {
+		t.Errorf("expected TF2 to stay clear as a baud rate generator")
+	}
+
+	if vm.TL2() != 0xFA || vm.TH2() != 0xFF {
+		t.Errorf("expected TH2:TL2 to reload from RCAP2, got %02X%02X", vm.TH2(), vm.TL2())
+	}
+}
+
+func TestTimer2BaudReceive(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_SCON, 0x50) // mode 1, REN
+	vm.WriteMem(SFR_RCAP2H, 0xFF)
+	vm.WriteMem(SFR_RCAP2L, 0xFA)
+	vm.WriteMem(SFR_TH2, 0xFF)
+	vm.WriteMem(SFR_TL2, 0xFA)
+	vm.WriteMem(SFR_T2CON, T2CON_RCLK_MASK|T2CON_TR2_MASK)
+	vm.QueueSerialFrame(0xA5, true)
+
+	feedNops(t, vm, 160)
+
+	if vm.SCON()&SCON_RI_MASK == 0 || vm.SBUF() != 0xA5 {
+		t.Errorf("expected 0xA5 received after 160 cycles, got SBUF %#02x, SCON %#02x", vm.SBUF(), vm.SCON())
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"testing"
+)
+
+func feedNops(t *testing.T, vm *Machine, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if err := vm.Feed([]byte{0x00}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTimer0Modes(t *testing.T) {
+	cases := []struct {
+		Name       string
+		TMOD       byte
+		TL0, TH0   byte
+		Cycles     int
+		ExpectedTL byte
+		ExpectedTH byte
+		TF0        bool
+	}{
+		{Name: "mode 1 count", TMOD: 0x01, TL0: 0x00, TH0: 0x00, Cycles: 3, ExpectedTL: 0x03, ExpectedTH: 0x00},
+		{Name: "mode 1 carry", TMOD: 0x01, TL0: 0xFF, TH0: 0x12, Cycles: 1, ExpectedTL: 0x00, ExpectedTH: 0x13},
+		{Name: "mode 1 overflow", TMOD: 0x01, TL0: 0xFE, TH0: 0xFF, Cycles: 2, ExpectedTL: 0x00, ExpectedTH: 0x00, TF0: true},
+		{Name: "mode 0 prescaler", TMOD: 0x00, TL0: 0x1F, TH0: 0x00, Cycles: 1, ExpectedTL: 0x00, ExpectedTH: 0x01},
+		{Name: "mode 0 keeps TL upper bits", TMOD: 0x00, TL0: 0xE0, TH0: 0x00, Cycles: 2, ExpectedTL: 0xE2, ExpectedTH: 0x00},
+		{Name: "mode 0 overflow", TMOD: 0x00, TL0: 0x1F, TH0: 0xFF, Cycles: 1, ExpectedTL: 0x00, ExpectedTH: 0x00, TF0: true},
+		{Name: "mode 2 reload", TMOD: 0x02, TL0: 0xFF, TH0: 0x9C, Cycles: 1, ExpectedTL: 0x9C, ExpectedTH: 0x9C, TF0: true},
+		{Name: "mode 2 after reload", TMOD: 0x02, TL0: 0xFF, TH0: 0x9C, Cycles: 3, ExpectedTL: 0x9E, ExpectedTH: 0x9C, TF0: true},
+	}
+
+	for _, tc := range cases {
+		vm := NewMachine()
+		vm.WriteMem(SFR_TMOD, tc.TMOD)
+		vm.WriteMem(SFR_TL0, tc.TL0)
+		vm.WriteMem(SFR_TH0, tc.TH0)
+		vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+
+		feedNops(t, vm, tc.Cycles)
+
+		if vm.TL0() != tc.ExpectedTL || vm.TH0() != tc.ExpectedTH {
+			t.Errorf("%s: expected TH0:TL0 to be %02X:%02X, got %02X:%02X", tc.Name, tc.ExpectedTH, tc.ExpectedTL, vm.TH0(), vm.TL0())
+		}
+
+		if tf0 := vm.TCON()&TCON_TF0_MASK != 0; tf0 != tc.TF0 {
+			t.Errorf("%s: expected TF0 to be %t, got %t", tc.Name, tc.TF0, tf0)
+		}
+	}
+}
+
+func TestTimerStopped(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_TMOD, 0x11)
+
+	feedNops(t, vm, 5)
+
+	if vm.TL0() != 0 || vm.TL1() != 0 {
+		t.Errorf("expected timers to hold without TR0/TR1, got TL0=%#02x TL1=%#02x", vm.TL0(), vm.TL1())
+	}
+}
+
+func TestTimer1Mode1(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_TMOD, 0x10)
+	vm.WriteMem(SFR_TL1, 0xFC)
+	vm.WriteMem(SFR_TH1, 0xFF)
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+
+	// MUL AB is 4 cycles
+	if err := vm.Feed([]byte{0xA4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if vm.TL1() != 0 || vm.TH1() != 0 || vm.TCON()&TCON_TF1_MASK == 0 {
+		t.Errorf("expected timer 1 to overflow after 4 cycles, got TH1:TL1=%02X:%02X TCON=%#08b", vm.TH1(), vm.TL1(), vm.TCON())
+	}
+
+	if vm.TCON()&TCON_TF0_MASK != 0 {
+		t.Errorf("expected TF0 to stay clear")
+	}
+}
+
+func TestTimerMode3Split(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_TMOD, 0x23) // timer 1 mode 2, timer 0 mode 3
+	vm.WriteMem(SFR_TL0, 0xFF)
+	vm.WriteMem(SFR_TH0, 0xFE)
+	vm.WriteMem(SFR_TL1, 0xFF)
+	vm.WriteMem(SFR_TH1, 0x80)
+	vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+
+	feedNops(t, vm, 1)
+
+	// TL0 overflows on its own, TH0 needs TR1
+	if vm.TL0() != 0x00 || vm.TH0() != 0xFE || vm.TCON()&TCON_TF0_MASK == 0 {
+		t.Errorf("expected TL0 to overflow into TF0 alone, got TH0:TL0=%02X:%02X TCON=%#08b", vm.TH0(), vm.TL0(), vm.TCON())
+	}
+
+	// timer 1 free-runs but cannot raise TF1
+	if vm.TL1() != 0x80 || vm.TCON()&TCON_TF1_MASK != 0 {
+		t.Errorf("expected timer 1 to reload without TF1, got TL1=%#02x TCON=%#08b", vm.TL1(), vm.TCON())
+	}
+
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+	feedNops(t, vm, 2)
+
+	if vm.TH0() != 0x00 || vm.TCON()&TCON_TF1_MASK == 0 {
+		t.Errorf("expected TH0 to overflow into TF1, got TH0=%#02x TCON=%#08b", vm.TH0(), vm.TCON())
+	}
+
+	if vm.TL0() != 0x00 {
+		t.Errorf("expected TL0 to stop without TR0, got %#02x", vm.TL0())
+	}
+
+	// timer 1 in mode 3 holds its count
+	vm.WriteMem(SFR_TMOD, 0x30)
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+	tl1 := vm.TL1()
+	feedNops(t, vm, 3)
+
+	if vm.TL1() != tl1 {
+		t.Errorf("expected timer 1 to stop in mode 3, TL1 went from %#02x to %#02x", tl1, vm.TL1())
+	}
+}
+
+func TestTimerGate(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_TMOD, 0x09) // GATE, mode 1
+	vm.WriteMem(SFR_TCON, TCON_TR0_MASK)
+
+	feedNops(t, vm, 2)
+	if vm.TL0() != 2 {
+		t.Errorf("expected timer to run with INT0 high, got TL0=%d", vm.TL0())
+	}
+
+	// CLR P3.2 holds INT0 low
+	if err := vm.Feed([]byte{0xC2, 0xB2}); err != nil {
+		t.Fatal(err)
+	}
+	tl0 := vm.TL0()
+
+	feedNops(t, vm, 3)
+	if vm.TL0() != tl0 {
+		t.Errorf("expected gated timer to stop with INT0 low, TL0 went from %d to %d", tl0, vm.TL0())
+	}
+}
+
+func TestCounterMode(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteMem(SFR_TMOD, 0x50) // timer 1 counter, mode 1
+	vm.WriteMem(SFR_TCON, TCON_TR1_MASK)
+
+	for i := 0; i < 3; i++ {
+		vm.SetT1(false)
+		feedNops(t, vm, 2)
+		vm.SetT1(true)
+		feedNops(t, vm, 2)
+	}
+
+	if vm.TL1() != 3 {
+		t.Errorf("expected 3 falling edges to be counted, got %d", vm.TL1())
+	}
+
+	// holding the pin low is not a pulse
+	vm.SetT1(false)
+	feedNops(t, vm, 5)
+
+	if vm.TL1() != 4 {
+		t.Errorf("expected a held-low pin to count once, got %d", vm.TL1())
+	}
+}
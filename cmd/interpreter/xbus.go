@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Anything that answers on the external data bus: an 8255 PPI, a UART,
+// an LCD controller or plain RAM. addr is relative to the start of the
+// region the device is mapped at
+type XDataDevice interface {
+	Read(addr uint16) (byte, error)
+	Write(addr uint16, value byte) error
+}
+
+type xdataRegion struct {
+	Start  uint16
+	Size   int
+	Device XDataDevice
+}
+
+// Address decoding for MOVX. Mapped regions take priority over
+// Machine.XRAM, which answers for the rest of the address space
+type XDataBus struct {
+	regions []xdataRegion // sorted by Start, never overlapping
+}
+
+func NewXDataBus() *XDataBus {
+	return &XDataBus{}
+}
+
+// Maps dev at start..start+size-1. Regions may shadow Machine.XRAM
+// but not each other
+func (b *XDataBus) Map(start uint16, size int, dev XDataDevice) error {
+	if size < 1 || int(start)+size > XRAM_MAX_SIZE {
+		return fmt.Errorf("region of %dB at %#04x does not fit the %dB external address space", size, start, XRAM_MAX_SIZE)
+	}
+
+	end := int(start) + size
+	for _, r := range b.regions {
+		if int(start) < int(r.Start)+r.Size && int(r.Start) < end {
+			return fmt.Errorf("region %#04x-%#04x overlaps %#04x-%#04x", start, end-1, r.Start, int(r.Start)+r.Size-1)
+		}
+	}
+
+	b.regions = append(b.regions, xdataRegion{Start: start, Size: size, Device: dev})
+	slices.SortFunc(b.regions, func(a, b xdataRegion) int {
+		return int(a.Start) - int(b.Start)
+	})
+
+	return nil
+}
+
+// Maps a fresh block of RAM, e.g. a second SRAM chip
+func (b *XDataBus) MapRAM(start uint16, size int) (*ExternalMemory, error) {
+	ram, err := NewExternalMemory(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Map(start, size, ram); err != nil {
+		return nil, err
+	}
+
+	return ram, nil
+}
+
+// Removes the region starting at start
+func (b *XDataBus) Unmap(start uint16) error {
+	for i, r := range b.regions {
+		if r.Start == start {
+			b.regions = slices.Delete(b.regions, i, i+1)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no region starts at %#04x", start)
+}
+
+// Device decoding addr and the address relative to its region
+func (b *XDataBus) decode(addr uint16) (XDataDevice, uint16, bool) {
+	for _, r := range b.regions {
+		if addr < r.Start {
+			break
+		}
+
+		if int(addr) < int(r.Start)+r.Size {
+			return r.Device, addr - r.Start, true
+		}
+	}
+
+	return nil, 0, false
+}
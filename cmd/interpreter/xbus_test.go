@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Four registers like an 8255 PPI: ports A-C and a write-only control word
+type testPPI struct {
+	regs   [4]byte
+	writes []uint16
+}
+
+func (p *testPPI) Read(addr uint16) (byte, error) {
+	if addr == 3 {
+		return 0, fmt.Errorf("control word is write-only")
+	}
+	return p.regs[addr], nil
+}
+
+func (p *testPPI) Write(addr uint16, value byte) error {
+	p.regs[addr] = value
+	p.writes = append(p.writes, addr)
+	return nil
+}
+
+func TestXDataBusMovx(t *testing.T) {
+	vm, err := NewMachineFor(DEVICE_8051)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ppi := &testPPI{}
+	if err := vm.XBus.Map(0x8000, 4, ppi); err != nil {
+		t.Fatal(err)
+	}
+
+	ram, err := vm.XBus.MapRAM(0x0000, 0x2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MOV DPTR,#8001h ; MOV A,#5Ah ; MOVX @DPTR,A
+	for _, instr := range [][]byte{{0x90, 0x80, 0x01}, {0x74, 0x5A}, {0xF0}} {
+		if err := vm.Feed(instr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ppi.regs[1] != 0x5A || len(ppi.writes) != 1 || ppi.writes[0] != 1 {
+		t.Errorf("expected MOVX @DPTR to write port B of the PPI, got %v (writes %v)", ppi.regs, ppi.writes)
+	}
+
+	// MOV P2,#80h ; MOV R0,#02h ; MOVX A,@R0 reads port C
+	ppi.regs[2] = 0xC3
+	for _, instr := range [][]byte{{0x75, SFR_P2, 0x80}, {0x78, 0x02}, {0xE2}} {
+		if err := vm.Feed(instr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if vm.ACC() != 0xC3 {
+		t.Errorf("expected MOVX A,@R0 to read port C (0xC3), got %#02x", vm.ACC())
+	}
+
+	// MOV DPTR,#1FFFh ; MOVX @DPTR,A lands in the RAM region
+	for _, instr := range [][]byte{{0x90, 0x1F, 0xFF}, {0xF0}} {
+		if err := vm.Feed(instr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if val, _ := ram.Read(0x1FFF); val != 0xC3 {
+		t.Errorf("expected 0xc3 in the RAM region at 1FFFh, got %#02x", val)
+	}
+
+	// device errors come back through MOVX
+	if err := vm.Feed([]byte{0x90, 0x80, 0x03}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Feed([]byte{0xE0}); err == nil {
+		t.Errorf("expected reading the PPI control word to fail")
+	}
+
+	// nothing answers at 4000h on an 8051 board with just these two
+	if _, err := vm.ReadXMem(0x4000); err == nil {
+		t.Errorf("expected reading unmapped 4000h to fail")
+	}
+}
+
+func TestXDataBusShadowsXRAM(t *testing.T) {
+	vm := NewMachine()
+	vm.WriteXMem(0x8000, 0x11)
+
+	ppi := &testPPI{}
+	if err := vm.XBus.Map(0x8000, 4, ppi); err != nil {
+		t.Fatal(err)
+	}
+
+	vm.WriteXMem(0x8000, 0x22)
+	vm.WriteXMem(0x8004, 0x33)
+
+	if ppi.regs[0] != 0x22 {
+		t.Errorf("expected the PPI to answer at 8000h, got %#02x", ppi.regs[0])
+	}
+
+	if val, _ := vm.XRAM.Read(0x8000); val != 0x11 {
+		t.Errorf("expected XRAM under the PPI to be left alone, got %#02x", val)
+	}
+
+	if val, _ := vm.ReadXMem(0x8004); val != 0x33 {
+		t.Errorf("expected XRAM right after the PPI, got %#02x", val)
+	}
+
+	if err := vm.XBus.Unmap(0x8000); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, _ := vm.ReadXMem(0x8000); val != 0x11 {
+		t.Errorf("expected XRAM back at 8000h once unmapped, got %#02x", val)
+	}
+
+	if err := vm.XBus.Unmap(0x8000); err == nil {
+		t.Errorf("expected unmapping twice to fail")
+	}
+}
+
+func TestXDataBusMap(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Start uint16
+		Size  int
+		Ok    bool
+	}{
+		{Name: "before", Start: 0x0FF0, Size: 0x10, Ok: true},
+		{Name: "after", Start: 0x1100, Size: 0x100, Ok: true},
+		{Name: "top of the address space", Start: 0xFFFF, Size: 1, Ok: true},
+		{Name: "overlaps start", Start: 0x0FF0, Size: 0x11, Ok: false},
+		{Name: "overlaps end", Start: 0x10FF, Size: 2, Ok: false},
+		{Name: "inside", Start: 0x1010, Size: 1, Ok: false},
+		{Name: "around", Start: 0x0000, Size: 0x2000, Ok: false},
+		{Name: "past 64KB", Start: 0xFFFF, Size: 2, Ok: false},
+		{Name: "empty", Start: 0x2000, Size: 0, Ok: false},
+	}
+
+	for _, tc := range cases {
+		bus := NewXDataBus()
+		if _, err := bus.MapRAM(0x1000, 0x100); err != nil {
+			t.Fatal(err)
+		}
+
+		err := bus.Map(tc.Start, tc.Size, &testPPI{})
+		if (err == nil) != tc.Ok {
+			t.Errorf("%s: expected ok to be %t, got error %v", tc.Name, tc.Ok, err)
+		}
+	}
+}
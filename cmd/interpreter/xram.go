@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+)
+
+// MOVX can address up to 64KB of external data memory
+const XRAM_MAX_SIZE int = 64 * 1024
+
+// External data memory, only reachable through MOVX
+type ExternalMemory struct {
+	ram []byte
+}
+
+// A size of 0 models a part with nothing on the external bus
+func NewExternalMemory(size int) (*ExternalMemory, error) {
+	if size < 0 || size > XRAM_MAX_SIZE {
+		return nil, fmt.Errorf("external memory size must be between 0B and %dB, got %dB", XRAM_MAX_SIZE, size)
+	}
+
+	xram := ExternalMemory{
+		ram: make([]byte, size),
+	}
+
+	return &xram, nil
+}
+
+// How many bytes of external memory are fitted
+func (x *ExternalMemory) Size() int {
+	return len(x.ram)
+}
+
+func (x *ExternalMemory) Read(addr uint16) (byte, error) {
+	if int(addr) >= len(x.ram) {
+		return 0, fmt.Errorf("external address %#04x exceeds external memory size of %dB", addr, len(x.ram))
+	}
+
+	return x.ram[addr], nil
+}
+
+func (x *ExternalMemory) Write(addr uint16, value byte) error {
+	if int(addr) >= len(x.ram) {
+		return fmt.Errorf("external address %#04x exceeds external memory size of %dB", addr, len(x.ram))
+	}
+
+	x.ram[addr] = value
+	return nil
+}
+
+// MOVX reads go to whatever is mapped at addr on XBus, or XRAM otherwise
+func (m *Machine) ReadXMem(addr uint16) (byte, error) {
+	if dev, offset, ok := m.XBus.decode(addr); ok {
+		val, err := dev.Read(offset)
+		if err != nil {
+			return 0, fmt.Errorf("external device at %#04x: %w", addr, err)
+		}
+		return val, nil
+	}
+
+	return m.XRAM.Read(addr)
+}
+
+func (m *Machine) WriteXMem(addr uint16, value byte) error {
+	if dev, offset, ok := m.XBus.decode(addr); ok {
+		if err := dev.Write(offset, value); err != nil {
+			return fmt.Errorf("external device at %#04x: %w", addr, err)
+		}
+		return nil
+	}
+
+	return m.XRAM.Write(addr, value)
+}
+
+// MOVX @Ri only carries the low address byte,
+// the high byte is whatever is currently in the P2 latch
+func (m *Machine) xaddrBank(reg uint8) (uint16, error) {
+	lo, err := m.ReadBankMem(reg)
+	if err != nil {
+		return 0, err
+	}
+
+	hi, err := m.readLatch(SFR_P2)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint16(hi)<<8 | uint16(lo), nil
+}
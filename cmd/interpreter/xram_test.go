@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewExternalMemorySize(t *testing.T) {
+	cases := []struct {
+		Size int
+		Ok   bool
+	}{
+		{Size: -1, Ok: false},
+		{Size: 0, Ok: true},
+		{Size: 2 * 1024, Ok: true},
+		{Size: XRAM_MAX_SIZE, Ok: true},
+		{Size: XRAM_MAX_SIZE + 1, Ok: false},
+	}
+
+	for _, tc := range cases {
+		xram, err := NewExternalMemory(tc.Size)
+		if tc.Ok && err != nil {
+			t.Errorf("size %d: unexpected error: %s", tc.Size, err)
+		}
+
+		if !tc.Ok && err == nil {
+			t.Errorf("size %d: expected error, nil given", tc.Size)
+		}
+
+		if tc.Ok && xram.Size() != tc.Size {
+			t.Errorf("expected size to be %d, got %d", tc.Size, xram.Size())
+		}
+	}
+}
+
+func TestExternalMemoryBounds(t *testing.T) {
+	xram, _ := NewExternalMemory(0x100)
+
+	if err := xram.Write(0x00FF, 0xAB); err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := xram.Read(0x00FF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if val != 0xAB {
+		t.Errorf("expected 0xab, got %#02x", val)
+	}
+
+	if err := xram.Write(0x0100, 0x01); err == nil {
+		t.Errorf("expected error writing past the end of external memory, nil given")
+	}
+
+	if _, err := xram.Read(0x0100); err == nil {
+		t.Errorf("expected error reading past the end of external memory, nil given")
+	}
+}
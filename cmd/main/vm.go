@@ -350,7 +350,7 @@ func main() {
 
 		sz, operands, err := op.Parse(program[pos:], pos)
 		if err != nil {
-			fmt.Printf("error encountered when parsing opcode %#02x: %s", err)
+			fmt.Printf("error encountered when parsing opcode %#02x: %s", byteCode, err)
 			continue
 		}
 